@@ -0,0 +1,129 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr"
+	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
+)
+
+type MethodLoggingInterceptorConfig struct {
+	// Logger is the optional logger the method calls will be logged with, if not given, will attempt to use the context
+	// logger. If neither are present, no logging will be done
+	Logger *logr.Logger
+	// LogSuccessfulCompletion optionally will log a message on successful call completion
+	LogSuccessfulCompletion bool
+	// LogErrorCompletion optionally will log a message on call error
+	LogErrorCompletion bool
+}
+
+func NewMethodLoggingInterceptor(config MethodLoggingInterceptorConfig) *MethodLoggingInterceptor {
+	interceptor := &MethodLoggingInterceptor{
+		logger:                  config.Logger,
+		logSuccessfulCompletion: config.LogSuccessfulCompletion,
+		logErrorCompletion:      config.LogErrorCompletion,
+	}
+
+	return interceptor
+}
+
+var _ connect.Interceptor = (*MethodLoggingInterceptor)(nil)
+
+type MethodLoggingInterceptor struct {
+	unimplemented.UnimplementedInterceptor
+	logger                  *logr.Logger
+	logSuccessfulCompletion bool
+	logErrorCompletion      bool
+}
+
+func (m *MethodLoggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		log := m.getLogger(ctx).WithValues("path", req.Spec().Procedure)
+
+		log.Info("request sent")
+
+		resp, err := next(ctx, req)
+
+		if err != nil && m.logErrorCompletion {
+			log.Error(err, "request completed with error")
+		}
+		if err == nil && m.logSuccessfulCompletion {
+			log.Info("request completed")
+		}
+
+		return resp, err
+	})
+}
+
+func (m *MethodLoggingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		log := m.getLogger(ctx).WithValues("path", spec.Procedure)
+
+		log.Info("stream started")
+
+		return &methodLoggingClientConn{
+			StreamingClientConn: next(ctx, spec),
+			interceptor:         m,
+			log:                 log,
+		}
+	})
+}
+
+func (m *MethodLoggingInterceptor) getLogger(ctx context.Context) logr.Logger {
+	if m.logger != nil {
+		return *m.logger
+	} else {
+		return logr.FromContextOrDiscard(ctx)
+	}
+}
+
+// methodLoggingClientConn wraps a streaming connection, recording the first error returned from the server so that
+// it can be logged once the response side of the stream is closed
+type methodLoggingClientConn struct {
+	connect.StreamingClientConn
+	interceptor *MethodLoggingInterceptor
+	log         logr.Logger
+
+	mu   sync.Mutex
+	err  error
+	once sync.Once
+}
+
+func (m *methodLoggingClientConn) Receive(msg any) error {
+	err := m.StreamingClientConn.Receive(msg)
+	if err != nil && !errors.Is(err, io.EOF) {
+		m.mu.Lock()
+		if m.err == nil {
+			m.err = err
+		}
+		m.mu.Unlock()
+	}
+	return err
+}
+
+func (m *methodLoggingClientConn) CloseResponse() error {
+	closeErr := m.StreamingClientConn.CloseResponse()
+
+	m.once.Do(func() {
+		m.mu.Lock()
+		err := m.err
+		m.mu.Unlock()
+
+		if err == nil && closeErr != nil {
+			err = closeErr
+		}
+
+		if err != nil && m.interceptor.logErrorCompletion {
+			m.log.Error(err, "stream ended with error")
+		}
+		if err == nil && m.interceptor.logSuccessfulCompletion {
+			m.log.Info("stream completed")
+		}
+	})
+
+	return closeErr
+}
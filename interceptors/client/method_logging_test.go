@@ -0,0 +1,157 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMethodLoggingUnary(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name     string
+		config   MethodLoggingInterceptorConfig
+		err      error
+		expected []string
+	}{
+		{
+			name:     "success not logged",
+			config:   MethodLoggingInterceptorConfig{},
+			expected: []string{"request sent"},
+		},
+		{
+			name: "success logged",
+			config: MethodLoggingInterceptorConfig{
+				LogSuccessfulCompletion: true,
+			},
+			expected: []string{"request sent", "request completed"},
+		},
+		{
+			name: "error logged",
+			config: MethodLoggingInterceptorConfig{
+				LogErrorCompletion: true,
+			},
+			err:      connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expected: []string{"request sent", "request completed with error"},
+		},
+		{
+			name: "error not logged",
+			config: MethodLoggingInterceptorConfig{
+				LogSuccessfulCompletion: true,
+			},
+			err:      connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expected: []string{"request sent"},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages := []string{}
+			log := funcr.NewJSON(func(obj string) {
+				line := map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				messages = append(messages, line["msg"].(string))
+			}, funcr.Options{})
+			tc.config.Logger = &log
+
+			unary := NewMethodLoggingInterceptor(tc.config).WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				return connect.NewResponse(&emptypb.Empty{}), nil
+			})
+
+			_, err := unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+			require.Equal(t, tc.err, err)
+			require.Equal(t, tc.expected, messages)
+		})
+	}
+}
+
+func TestMethodLoggingStreaming(t *testing.T) {
+	t.Parallel()
+
+	receiveErr := connect.NewError(connect.CodeUnavailable, errors.New("receive failed"))
+	closeErr := connect.NewError(connect.CodeInternal, errors.New("close failed"))
+
+	testData := []struct {
+		name          string
+		conn          *testClientConn
+		expected      []string
+		expectedError string
+	}{
+		{
+			name:     "completed",
+			conn:     &testClientConn{responses: []proto.Message{wrapperspb.String("a")}},
+			expected: []string{"stream started", "stream completed"},
+		},
+		{
+			name:          "receive error logged on close",
+			conn:          &testClientConn{receiveErr: receiveErr},
+			expected:      []string{"stream started", "stream ended with error"},
+			expectedError: receiveErr.Error(),
+		},
+		{
+			name:          "close error",
+			conn:          &testClientConn{closeResponseErr: closeErr},
+			expected:      []string{"stream started", "stream ended with error"},
+			expectedError: closeErr.Error(),
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			messages := []string{}
+			errs := []string{}
+			log := funcr.NewJSON(func(obj string) {
+				line := map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				messages = append(messages, line["msg"].(string))
+				if errMsg, ok := line["error"].(string); ok {
+					errs = append(errs, errMsg)
+				}
+			}, funcr.Options{})
+
+			inter := NewMethodLoggingInterceptor(MethodLoggingInterceptorConfig{
+				Logger:                  &log,
+				LogSuccessfulCompletion: true,
+				LogErrorCompletion:      true,
+			})
+			conn := inter.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+				tc.conn.spec = spec
+				return tc.conn
+			})(context.Background(), connect.Spec{Procedure: "/pkg.v1.Service/Stream"})
+
+			for {
+				if err := conn.Receive(&wrapperspb.StringValue{}); err != nil {
+					if !errors.Is(err, io.EOF) {
+						// Nothing is logged until the response side is closed
+						require.Equal(t, []string{"stream started"}, messages)
+					}
+					break
+				}
+			}
+
+			// Closing again should not log again
+			_ = conn.CloseResponse()
+			_ = conn.CloseResponse()
+
+			require.Equal(t, tc.expected, messages)
+			if tc.expectedError != "" {
+				require.Equal(t, []string{tc.expectedError}, errs)
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"runtime"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr"
+	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
+)
+
+const (
+	DefaultPanicStackBufferSize = 8192
+)
+
+type PanicInterceptorConfig struct {
+	// Logger is the optional logger the panics will be logged with, if not given, will attempt to use the context
+	// logger. If neither are present, no logging will be done
+	Logger *logr.Logger
+	// StackBufferSize is the optional stack size configuration. If not given will default to DefaultPanicStackBufferSize
+	StackBufferSize *int
+}
+
+func NewPanicInterceptor(config PanicInterceptorConfig) *PanicInterceptor {
+	interceptor := &PanicInterceptor{
+		logger: config.Logger,
+	}
+
+	size := config.StackBufferSize
+	if size == nil {
+		interceptor.stackBufferSize = DefaultPanicStackBufferSize
+	} else {
+		interceptor.stackBufferSize = *size
+	}
+
+	return interceptor
+}
+
+var _ connect.Interceptor = (*PanicInterceptor)(nil)
+
+// PanicInterceptor recovers panics raised while making outgoing calls, either in interceptors further down the chain or
+// while sending/receiving stream messages, and converts them into connect.CodeInternal errors
+type PanicInterceptor struct {
+	unimplemented.UnimplementedInterceptor
+	logger          *logr.Logger
+	stackBufferSize int
+}
+
+func (p *PanicInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (resp connect.AnyResponse, err error) {
+		defer p.recover(p.getLogger(ctx), &err)
+
+		resp, err = next(ctx, req)
+		return resp, err
+	})
+}
+
+func (p *PanicInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) (conn connect.StreamingClientConn) {
+		log := p.getLogger(ctx)
+		defer p.recoverConn(log, spec, &conn)
+
+		return &panicClientConn{
+			StreamingClientConn: next(ctx, spec),
+			interceptor:         p,
+			log:                 log,
+		}
+	})
+}
+
+// recover must be called directly via defer. If a panic is in progress, it is logged and err is overwritten with a
+// connect.CodeInternal error
+func (p *PanicInterceptor) recover(log logr.Logger, err *error) {
+	if r := recover(); r != nil {
+		*err = p.panicError(log, r)
+	}
+}
+
+// recoverConn must be called directly via defer. If a panic is in progress while creating a streaming connection, such
+// as in an interceptor further down the chain, it is logged and conn is overwritten with a connection failing every
+// call with a connect.CodeInternal error
+func (p *PanicInterceptor) recoverConn(log logr.Logger, spec connect.Spec, conn *connect.StreamingClientConn) {
+	if r := recover(); r != nil {
+		*conn = &failedClientConn{
+			spec:            spec,
+			err:             p.panicError(log, r),
+			requestHeader:   http.Header{},
+			responseHeader:  http.Header{},
+			responseTrailer: http.Header{},
+		}
+	}
+}
+
+// panicError logs the recovered panic value along with the stack, returning the error to fail the call with. It must
+// be called from the deferred function that recovered the panic, so the stack of the panicking goroutine is captured
+func (p *PanicInterceptor) panicError(log logr.Logger, r any) error {
+	stack := make([]byte, p.stackBufferSize)
+	stack = stack[:runtime.Stack(stack, false)]
+	panicErr := fmt.Errorf("recovering from panic: %v", r)
+	log.Error(panicErr, "recovering from panic", "stack", string(stack))
+	return connect.NewError(connect.CodeInternal, panicErr)
+}
+
+func (p *PanicInterceptor) getLogger(ctx context.Context) logr.Logger {
+	if p.logger != nil {
+		return *p.logger
+	} else {
+		return logr.FromContextOrDiscard(ctx)
+	}
+}
+
+// panicClientConn wraps a streaming connection, recovering panics raised by any of the underlying connections methods
+type panicClientConn struct {
+	connect.StreamingClientConn
+	interceptor *PanicInterceptor
+	log         logr.Logger
+}
+
+func (p *panicClientConn) Send(msg any) (err error) {
+	defer p.interceptor.recover(p.log, &err)
+	return p.StreamingClientConn.Send(msg)
+}
+
+func (p *panicClientConn) CloseRequest() (err error) {
+	defer p.interceptor.recover(p.log, &err)
+	return p.StreamingClientConn.CloseRequest()
+}
+
+func (p *panicClientConn) Receive(msg any) (err error) {
+	defer p.interceptor.recover(p.log, &err)
+	return p.StreamingClientConn.Receive(msg)
+}
+
+func (p *panicClientConn) CloseResponse() (err error) {
+	defer p.interceptor.recover(p.log, &err)
+	return p.StreamingClientConn.CloseResponse()
+}
+
+// failedClientConn stands in for a streaming connection that could not be created because of a panic. Sends & receives
+// fail with the panic error, and closing is a no-op
+type failedClientConn struct {
+	spec            connect.Spec
+	err             error
+	requestHeader   http.Header
+	responseHeader  http.Header
+	responseTrailer http.Header
+}
+
+func (f *failedClientConn) Spec() connect.Spec {
+	return f.spec
+}
+
+func (f *failedClientConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (f *failedClientConn) Send(any) error {
+	return f.err
+}
+
+func (f *failedClientConn) RequestHeader() http.Header {
+	return f.requestHeader
+}
+
+func (f *failedClientConn) CloseRequest() error {
+	return nil
+}
+
+func (f *failedClientConn) Receive(any) error {
+	return f.err
+}
+
+func (f *failedClientConn) ResponseHeader() http.Header {
+	return f.responseHeader
+}
+
+func (f *failedClientConn) ResponseTrailer() http.Header {
+	return f.responseTrailer
+}
+
+func (f *failedClientConn) CloseResponse() error {
+	return nil
+}
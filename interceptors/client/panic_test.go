@@ -0,0 +1,99 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestPanicInterceptor(t *testing.T) {
+	t.Parallel()
+
+	t.Run("unary", func(t *testing.T) {
+		t.Parallel()
+
+		unary := NewPanicInterceptor(PanicInterceptorConfig{}).WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			panic("boom")
+		})
+
+		var err error
+		require.NotPanics(t, func() {
+			_, err = unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		})
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+	})
+
+	testData := []struct {
+		method string
+		call   func(conn connect.StreamingClientConn) error
+	}{
+		{
+			method: "Send",
+			call: func(conn connect.StreamingClientConn) error {
+				return conn.Send(&emptypb.Empty{})
+			},
+		},
+		{
+			method: "CloseRequest",
+			call: func(conn connect.StreamingClientConn) error {
+				return conn.CloseRequest()
+			},
+		},
+		{
+			method: "Receive",
+			call: func(conn connect.StreamingClientConn) error {
+				return conn.Receive(&emptypb.Empty{})
+			},
+		},
+		{
+			method: "CloseResponse",
+			call: func(conn connect.StreamingClientConn) error {
+				return conn.CloseResponse()
+			},
+		},
+	}
+	for _, tc := range testData {
+		t.Run("streaming "+tc.method, func(t *testing.T) {
+			t.Parallel()
+
+			conn := NewPanicInterceptor(PanicInterceptorConfig{}).WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+				return &testClientConn{spec: spec, panicOn: tc.method}
+			})(context.Background(), connect.Spec{Procedure: "/pkg.v1.Service/Stream"})
+
+			var err error
+			require.NotPanics(t, func() {
+				err = tc.call(conn)
+			})
+			require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+			require.ErrorContains(t, err, tc.method)
+		})
+	}
+
+	t.Run("streaming connection creation", func(t *testing.T) {
+		t.Parallel()
+
+		inter := NewPanicInterceptor(PanicInterceptorConfig{})
+		client := inter.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+			panic("boom")
+		})
+
+		spec := connect.Spec{Procedure: "/pkg.v1.Service/Stream"}
+		var conn connect.StreamingClientConn
+		require.NotPanics(t, func() {
+			conn = client(context.Background(), spec)
+		})
+
+		require.Equal(t, spec, conn.Spec())
+		conn.RequestHeader().Set("X-Test", "1")
+
+		err := conn.Send(&emptypb.Empty{})
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+		require.ErrorContains(t, err, "boom")
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(conn.Receive(&emptypb.Empty{})))
+		require.NoError(t, conn.CloseRequest())
+		require.NoError(t, conn.CloseResponse())
+	})
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"context"
+	"sync/atomic"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr"
+	"github.com/nicjohnson145/connecthelp/interceptors/matcher"
+	"github.com/nicjohnson145/connecthelp/interceptors/payload"
+	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type PayloadLoggingInterceptorConfig struct {
+	// Logger is the optional logger the method calls will be logged with, if not given, will attempt to use the context
+	// logger. If neither are present, no logging will be done
+	Logger *logr.Logger
+	// RequestMethods is a comma separated list of methods to log requests for. The special value of '*' means log all
+	// request payloads
+	RequestMethods string
+	// ResponseMethods is a comma separated list of methods to log responses for. The special value of '*' means log all
+	// response payloads
+	ResponseMethods string
+	// RequestMatcher optionally selects the methods to log requests for, taking precedence over RequestMethods
+	RequestMatcher *matcher.MethodMatcher
+	// ResponseMatcher optionally selects the methods to log responses for, taking precedence over ResponseMethods
+	ResponseMatcher *matcher.MethodMatcher
+	// Pretty eschew's the provided logger & context logger, and instead prints the output to stdout as a
+	// human-readable indented object. Mostly intended for development debugging where log aggregators maybe not be in
+	// play. Combine with a Sink to print somewhere other than stdout
+	Pretty bool
+	// RedactFields optionally lists fields to redact, keyed by message full name (e.g. pkg.v1.LoginRequest) with field
+	// paths relative to that message (e.g. password, or credentials.token). Fields marked with the standard debug_redact
+	// option are always redacted. See payload.LoggerConfig for details
+	RedactFields map[string][]string
+	// RedactExtension is an optional custom field option extension marking fields to redact
+	RedactExtension protoreflect.ExtensionType
+	// RedactionPlaceholder is the optional value redacted fields are replaced with. If not given will default to
+	// payload.DefaultRedactionPlaceholder
+	RedactionPlaceholder string
+	// MaxPayloadSize optionally limits the total size in bytes of the formatted payload, counting both keys & values
+	MaxPayloadSize int
+	// MaxStringLength optionally limits the length in bytes of each string field
+	MaxStringLength int
+	// MaxBytesLength optionally limits the length of each bytes field
+	MaxBytesLength int
+	// MaxRepeatedElements optionally limits the number of elements logged for each repeated field
+	MaxRepeatedElements int
+	// Formatter optionally controls how payloads are rendered. If not given will default to a
+	// payload.ProtoJSONFormatter, indented when Pretty is set
+	Formatter payload.Formatter
+	// Sink optionally receives payloads in place of the provided logger & context logger. If not given and Pretty is
+	// set, will default to a payload.WriterSink on stdout
+	Sink payload.Sink
+}
+
+func NewPayloadLoggingInterceptor(config PayloadLoggingInterceptorConfig) *PayloadLoggingInterceptor {
+	requestMatcher := config.RequestMatcher
+	if requestMatcher == nil {
+		requestMatcher = matcher.FromMethodList(config.RequestMethods)
+	}
+	responseMatcher := config.ResponseMatcher
+	if responseMatcher == nil {
+		responseMatcher = matcher.FromMethodList(config.ResponseMethods)
+	}
+
+	return &PayloadLoggingInterceptor{
+		logger:          config.Logger,
+		requestMatcher:  requestMatcher,
+		responseMatcher: responseMatcher,
+		payloadLogger: payload.NewLogger(payload.LoggerConfig{
+			Pretty:               config.Pretty,
+			RedactFields:         config.RedactFields,
+			RedactExtension:      config.RedactExtension,
+			RedactionPlaceholder: config.RedactionPlaceholder,
+			MaxPayloadSize:       config.MaxPayloadSize,
+			MaxStringLength:      config.MaxStringLength,
+			MaxBytesLength:       config.MaxBytesLength,
+			MaxRepeatedElements:  config.MaxRepeatedElements,
+			Formatter:            config.Formatter,
+			Sink:                 config.Sink,
+		}),
+	}
+}
+
+var _ connect.Interceptor = (*PayloadLoggingInterceptor)(nil)
+
+type PayloadLoggingInterceptor struct {
+	unimplemented.UnimplementedInterceptor
+	logger *logr.Logger
+
+	requestMatcher  *matcher.MethodMatcher
+	responseMatcher *matcher.MethodMatcher
+	payloadLogger   *payload.Logger
+}
+
+func (p *PayloadLoggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		log := p.getLogger(ctx)
+
+		if p.requestMatcher.MatchesSpec(req.Spec()) {
+			p.payloadLogger.Log(log, req.Any(), "request object")
+		}
+		resp, err := next(ctx, req)
+		if err == nil && p.responseMatcher.MatchesSpec(req.Spec()) {
+			p.payloadLogger.Log(log, resp.Any(), "response object")
+		}
+
+		return resp, err
+	})
+}
+
+func (p *PayloadLoggingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		conn := next(ctx, spec)
+
+		logRequests := p.requestMatcher.MatchesSpec(spec)
+		logResponses := p.responseMatcher.MatchesSpec(spec)
+		if !logRequests && !logResponses {
+			return conn
+		}
+
+		return &payloadLoggingClientConn{
+			StreamingClientConn: conn,
+			interceptor:         p,
+			log:                 p.getLogger(ctx),
+			logRequests:         logRequests,
+			logResponses:        logResponses,
+		}
+	})
+}
+
+func (p *PayloadLoggingInterceptor) getLogger(ctx context.Context) logr.Logger {
+	if p.logger != nil {
+		return *p.logger
+	} else {
+		return logr.FromContextOrDiscard(ctx)
+	}
+}
+
+const (
+	streamDirectionSend    = "send"
+	streamDirectionReceive = "receive"
+)
+
+// payloadLoggingClientConn wraps a streaming connection, logging each message as it is sent to or received from the
+// server. Sends and receives may happen concurrently on client connections, so the indexes are tracked atomically
+type payloadLoggingClientConn struct {
+	connect.StreamingClientConn
+	interceptor  *PayloadLoggingInterceptor
+	log          logr.Logger
+	logRequests  bool
+	logResponses bool
+	sendIndex    atomic.Int64
+	receiveIndex atomic.Int64
+}
+
+func (p *payloadLoggingClientConn) Send(msg any) error {
+	index := p.sendIndex.Add(1) - 1
+	if p.logRequests {
+		p.interceptor.payloadLogger.Log(p.log, msg, "request object", "direction", streamDirectionSend, "index", index)
+	}
+
+	return p.StreamingClientConn.Send(msg)
+}
+
+func (p *payloadLoggingClientConn) Receive(msg any) error {
+	if err := p.StreamingClientConn.Receive(msg); err != nil {
+		return err
+	}
+
+	index := p.receiveIndex.Add(1) - 1
+	if p.logResponses {
+		p.interceptor.payloadLogger.Log(
+			p.log, msg, "response object", "direction", streamDirectionReceive, "index", index,
+		)
+	}
+
+	return nil
+}
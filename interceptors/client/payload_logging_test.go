@@ -0,0 +1,172 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// testClientConn is an in-memory StreamingClientConn for tests, receiving the given responses in order followed by
+// receiveErr (io.EOF if not given), and recording the messages sent. Setting panicOn panics with the given method name
+// when that method is called
+type testClientConn struct {
+	spec             connect.Spec
+	responses        []proto.Message
+	receiveErr       error
+	closeResponseErr error
+	panicOn          string
+
+	mu   sync.Mutex
+	sent []any
+}
+
+func (t *testClientConn) Spec() connect.Spec {
+	return t.spec
+}
+
+func (t *testClientConn) Peer() connect.Peer {
+	return connect.Peer{Addr: "127.0.0.1:1234", Protocol: connect.ProtocolConnect}
+}
+
+func (t *testClientConn) Send(msg any) error {
+	t.maybePanic("Send")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sent = append(t.sent, msg)
+	return nil
+}
+
+func (t *testClientConn) RequestHeader() http.Header {
+	return http.Header{}
+}
+
+func (t *testClientConn) CloseRequest() error {
+	t.maybePanic("CloseRequest")
+	return nil
+}
+
+func (t *testClientConn) Receive(msg any) error {
+	t.maybePanic("Receive")
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if len(t.responses) == 0 {
+		if t.receiveErr != nil {
+			return t.receiveErr
+		}
+		return io.EOF
+	}
+	proto.Reset(msg.(proto.Message))
+	proto.Merge(msg.(proto.Message), t.responses[0])
+	t.responses = t.responses[1:]
+	return nil
+}
+
+func (t *testClientConn) ResponseHeader() http.Header {
+	return http.Header{}
+}
+
+func (t *testClientConn) ResponseTrailer() http.Header {
+	return http.Header{}
+}
+
+func (t *testClientConn) CloseResponse() error {
+	t.maybePanic("CloseResponse")
+	return t.closeResponseErr
+}
+
+func (t *testClientConn) maybePanic(method string) {
+	if t.panicOn == method {
+		panic(method)
+	}
+}
+
+func TestPayloadLoggingStreaming(t *testing.T) {
+	t.Parallel()
+
+	type logLine struct {
+		Msg       string
+		Direction string
+		Index     int
+		Object    string
+	}
+
+	const messages = 20
+
+	mu := sync.Mutex{}
+	lines := []logLine{}
+	log := funcr.NewJSON(func(obj string) {
+		line := logLine{}
+		require.NoError(t, json.Unmarshal([]byte(obj), &line))
+
+		mu.Lock()
+		defer mu.Unlock()
+		lines = append(lines, line)
+	}, funcr.Options{})
+
+	inter := NewPayloadLoggingInterceptor(PayloadLoggingInterceptorConfig{
+		Logger:          &log,
+		RequestMethods:  "*",
+		ResponseMethods: "*",
+		RedactFields: map[string][]string{
+			"google.protobuf.StringValue": {"value"},
+		},
+	})
+
+	responses := []proto.Message{}
+	for range messages {
+		responses = append(responses, wrapperspb.String("secret"))
+	}
+	conn := inter.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		return &testClientConn{spec: spec, responses: responses}
+	})(context.Background(), connect.Spec{Procedure: "/pkg.v1.Service/Stream"})
+
+	// Sends & receives happen concurrently, each direction should still be indexed without gaps or duplicates
+	wg := sync.WaitGroup{}
+	for range messages {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			require.NoError(t, conn.Send(wrapperspb.String("secret")))
+		}()
+		go func() {
+			defer wg.Done()
+			require.NoError(t, conn.Receive(&wrapperspb.StringValue{}))
+		}()
+	}
+	wg.Wait()
+
+	indexes := map[string][]int{}
+	for _, line := range lines {
+		require.Equal(t, `"[REDACTED]"`, line.Object)
+		switch line.Direction {
+		case "send":
+			require.Equal(t, "request object", line.Msg)
+		case "receive":
+			require.Equal(t, "response object", line.Msg)
+		}
+		indexes[line.Direction] = append(indexes[line.Direction], line.Index)
+	}
+
+	expected := []int{}
+	for i := range messages {
+		expected = append(expected, i)
+	}
+	for _, direction := range []string{"send", "receive"} {
+		sort.Ints(indexes[direction])
+		require.Equal(t, expected, indexes[direction], direction)
+	}
+}
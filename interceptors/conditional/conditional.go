@@ -0,0 +1,113 @@
+// Package conditional provides When, for applying an interceptor to only a subset of calls
+package conditional
+
+import (
+	"context"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/nicjohnson145/connecthelp/interceptors/matcher"
+)
+
+// CallInfo is the information about a call available to a Predicate
+type CallInfo struct {
+	Spec connect.Spec
+	// Peer is the other party to the call. It is empty for streaming client calls, as the connection has not yet been
+	// established when the predicate is evaluated
+	Peer connect.Peer
+	// Header is the request header of the call. It is empty for streaming client calls, as headers are set by the
+	// caller after the predicate is evaluated
+	Header http.Header
+}
+
+// Predicate decides whether a call should be intercepted
+type Predicate func(info CallInfo) bool
+
+// When returns an interceptor that delegates to the given interceptor only for calls matching predicate, and passes
+// all other calls straight through
+func When(predicate Predicate, interceptor connect.Interceptor) connect.Interceptor {
+	return &conditionalInterceptor{
+		predicate:   predicate,
+		interceptor: interceptor,
+	}
+}
+
+// MatchesMethod matches calls whose procedure is matched by the given MethodMatcher
+func MatchesMethod(m *matcher.MethodMatcher) Predicate {
+	return func(info CallInfo) bool {
+		return m.MatchesSpec(info.Spec)
+	}
+}
+
+// HasHeader matches calls where the given request header is present
+func HasHeader(name string) Predicate {
+	return func(info CallInfo) bool {
+		return len(info.Header.Values(name)) > 0
+	}
+}
+
+// HeaderEquals matches calls where the given request header has the given value
+func HeaderEquals(name string, value string) Predicate {
+	return func(info CallInfo) bool {
+		for _, v := range info.Header.Values(name) {
+			if v == value {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+var _ connect.Interceptor = (*conditionalInterceptor)(nil)
+
+type conditionalInterceptor struct {
+	predicate   Predicate
+	interceptor connect.Interceptor
+}
+
+func (c *conditionalInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	wrapped := c.interceptor.WrapUnary(next)
+
+	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		info := CallInfo{
+			Spec:   req.Spec(),
+			Peer:   req.Peer(),
+			Header: req.Header(),
+		}
+		if c.predicate(info) {
+			return wrapped(ctx, req)
+		}
+		return next(ctx, req)
+	})
+}
+
+func (c *conditionalInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	wrapped := c.interceptor.WrapStreamingHandler(next)
+
+	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		info := CallInfo{
+			Spec:   conn.Spec(),
+			Peer:   conn.Peer(),
+			Header: conn.RequestHeader(),
+		}
+		if c.predicate(info) {
+			return wrapped(ctx, conn)
+		}
+		return next(ctx, conn)
+	})
+}
+
+func (c *conditionalInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	wrapped := c.interceptor.WrapStreamingClient(next)
+
+	return connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		info := CallInfo{
+			Spec:   spec,
+			Header: http.Header{},
+		}
+		if c.predicate(info) {
+			return wrapped(ctx, spec)
+		}
+		return next(ctx, spec)
+	})
+}
@@ -0,0 +1,230 @@
+package conditional
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/nicjohnson145/connecthelp/interceptors/matcher"
+	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type countingInterceptor struct {
+	unimplemented.UnimplementedInterceptor
+	calls int
+}
+
+func (c *countingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		c.calls++
+		return next(ctx, req)
+	})
+}
+
+func (c *countingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		c.calls++
+		return next(ctx, conn)
+	})
+}
+
+func (c *countingInterceptor) WrapStreamingClient(next connect.StreamingClientFunc) connect.StreamingClientFunc {
+	return connect.StreamingClientFunc(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+		c.calls++
+		return next(ctx, spec)
+	})
+}
+
+// fakeHandlerConn is a StreamingHandlerConn for tests, only Spec, Peer & RequestHeader are implemented
+type fakeHandlerConn struct {
+	connect.StreamingHandlerConn
+	spec   connect.Spec
+	header http.Header
+}
+
+func (f *fakeHandlerConn) Spec() connect.Spec {
+	return f.spec
+}
+
+func (f *fakeHandlerConn) Peer() connect.Peer {
+	return connect.Peer{}
+}
+
+func (f *fakeHandlerConn) RequestHeader() http.Header {
+	return f.header
+}
+
+func TestWhen(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name      string
+		predicate Predicate
+		header    map[string]string
+		expected  int
+	}{
+		{
+			name:      "header present",
+			predicate: HasHeader("X-Debug"),
+			header:    map[string]string{"X-Debug": "1"},
+			expected:  1,
+		},
+		{
+			name:      "header missing",
+			predicate: HasHeader("X-Debug"),
+			expected:  0,
+		},
+		{
+			name:      "header value match",
+			predicate: HeaderEquals("X-Debug", "slow"),
+			header:    map[string]string{"X-Debug": "slow"},
+			expected:  1,
+		},
+		{
+			name:      "header value mismatch",
+			predicate: HeaderEquals("X-Debug", "slow"),
+			header:    map[string]string{"X-Debug": "fast"},
+			expected:  0,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inner := &countingInterceptor{}
+			handlerCalled := false
+			unary := When(tc.predicate, inner).WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+				handlerCalled = true
+				return connect.NewResponse(&emptypb.Empty{}), nil
+			})
+
+			req := connect.NewRequest(&emptypb.Empty{})
+			for k, v := range tc.header {
+				req.Header().Set(k, v)
+			}
+
+			_, err := unary(context.Background(), req)
+			require.NoError(t, err)
+			require.True(t, handlerCalled)
+			require.Equal(t, tc.expected, inner.calls)
+		})
+	}
+}
+
+func TestWhenStreamingHandler(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name      string
+		predicate Predicate
+		procedure string
+		header    map[string]string
+		expected  int
+	}{
+		{
+			name:      "header present",
+			predicate: HasHeader("X-Debug"),
+			header:    map[string]string{"X-Debug": "1"},
+			expected:  1,
+		},
+		{
+			name:      "header missing",
+			predicate: HasHeader("X-Debug"),
+			expected:  0,
+		},
+		{
+			name:      "header value match",
+			predicate: HeaderEquals("X-Debug", "slow"),
+			header:    map[string]string{"X-Debug": "slow"},
+			expected:  1,
+		},
+		{
+			name:      "method match",
+			predicate: MatchesMethod(matcher.Exact("/pkg.v1.Service/Stream")),
+			procedure: "/pkg.v1.Service/Stream",
+			expected:  1,
+		},
+		{
+			name:      "method mismatch",
+			predicate: MatchesMethod(matcher.Exact("/pkg.v1.Service/Stream")),
+			procedure: "/pkg.v1.Service/Other",
+			expected:  0,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inner := &countingInterceptor{}
+			handlerCalled := false
+			inter := When(tc.predicate, inner)
+			stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+				handlerCalled = true
+				return nil
+			})
+
+			conn := &fakeHandlerConn{
+				spec:   connect.Spec{Procedure: tc.procedure},
+				header: http.Header{},
+			}
+			for k, v := range tc.header {
+				conn.header.Set(k, v)
+			}
+
+			require.NoError(t, stream(context.Background(), conn))
+			require.True(t, handlerCalled)
+			require.Equal(t, tc.expected, inner.calls)
+		})
+	}
+}
+
+func TestWhenStreamingClient(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name      string
+		predicate Predicate
+		procedure string
+		expected  int
+	}{
+		{
+			name:      "method match",
+			predicate: MatchesMethod(matcher.Exact("/pkg.v1.Service/Stream")),
+			procedure: "/pkg.v1.Service/Stream",
+			expected:  1,
+		},
+		{
+			name:      "method mismatch",
+			predicate: MatchesMethod(matcher.Exact("/pkg.v1.Service/Stream")),
+			procedure: "/pkg.v1.Service/Other",
+			expected:  0,
+		},
+		{
+			// Headers are set by the caller once the connection is returned, so are never visible to the predicate
+			name:      "header never present",
+			predicate: HasHeader("X-Debug"),
+			procedure: "/pkg.v1.Service/Stream",
+			expected:  0,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inner := &countingInterceptor{}
+			var gotSpec connect.Spec
+			inter := When(tc.predicate, inner)
+			client := inter.WrapStreamingClient(func(ctx context.Context, spec connect.Spec) connect.StreamingClientConn {
+				gotSpec = spec
+				return nil
+			})
+
+			client(context.Background(), connect.Spec{Procedure: tc.procedure})
+			require.Equal(t, tc.procedure, gotSpec.Procedure)
+			require.Equal(t, tc.expected, inner.calls)
+		})
+	}
+}
@@ -0,0 +1,233 @@
+// Package matcher provides MethodMatcher, used by interceptors to decide which procedures they apply to
+package matcher
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	// NegationPrefix marks a pattern as an exclusion
+	NegationPrefix = "!"
+	// RegexPrefix marks a pattern as a regular expression, matched anywhere in the procedure unless anchored
+	RegexPrefix = "re:"
+	// Wildcard matches every procedure
+	Wildcard = "*"
+)
+
+type procedureMatcher func(procedure string) bool
+
+type specMatcher func(spec connect.Spec) bool
+
+// MethodMatcher matches procedures against a set of include & exclude rules. Rules are either patterns over the
+// procedure name (e.g. /pkg.v1.Service/Method), or MethodPredicates over the method descriptor. Exclusions always take
+// precedence over inclusions, and a matcher with only exclusions matches every procedure not excluded. A nil or empty
+// MethodMatcher matches nothing.
+//
+// MethodPredicates need the method descriptor from connect.Spec.Schema, which is only populated for handlers & clients
+// built with connect.WithSchema (as generated code does). Without a schema, predicates fail closed: included
+// predicates never match, and excluded predicates always match, so a procedure is never wrongly let through an
+// exclusion such as Exclude(HasExtension(sensitive)).
+type MethodMatcher struct {
+	includes []specMatcher
+	excludes []specMatcher
+}
+
+// New builds a MethodMatcher from the given patterns. Each pattern is one of
+//
+//   - '*', matching all procedures
+//   - an exact procedure name, such as /pkg.v1.Service/Method
+//   - a path glob as understood by path.Match, such as /pkg.v1.Service/* or /pkg.v1.*/Get*
+//   - a regular expression prefixed with 're:', such as re:^/pkg\.v[0-9]+\.
+//
+// Any pattern may be prefixed with '!' to turn it into an exclusion. Empty patterns are ignored
+func New(patterns ...string) (*MethodMatcher, error) {
+	m := &MethodMatcher{}
+
+	for _, pattern := range patterns {
+		pattern = strings.TrimSpace(pattern)
+		if pattern == "" {
+			continue
+		}
+
+		exclude := strings.HasPrefix(pattern, NegationPrefix)
+		if exclude {
+			pattern = strings.TrimPrefix(pattern, NegationPrefix)
+		}
+
+		match, err := compile(pattern)
+		if err != nil {
+			return nil, err
+		}
+
+		if exclude {
+			m.excludes = append(m.excludes, byProcedure(match))
+		} else {
+			m.includes = append(m.includes, byProcedure(match))
+		}
+	}
+
+	return m, nil
+}
+
+// MustNew is like New, but panics if any pattern is invalid
+func MustNew(patterns ...string) *MethodMatcher {
+	m, err := New(patterns...)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// Parse builds a MethodMatcher from a comma separated list of patterns, as accepted by New
+func Parse(str string) (*MethodMatcher, error) {
+	return New(strings.Split(str, ",")...)
+}
+
+// FromMethodList builds a MethodMatcher from a comma separated list of exact procedure names, where the special value
+// '*' matches all procedures. This mirrors the format of the string based method configuration fields
+func FromMethodList(str string) *MethodMatcher {
+	switch str {
+	case "":
+		return &MethodMatcher{}
+	case Wildcard:
+		return All()
+	default:
+		return Exact(strings.Split(str, ",")...)
+	}
+}
+
+// All returns a MethodMatcher matching every procedure
+func All() *MethodMatcher {
+	return &MethodMatcher{
+		includes: []specMatcher{byProcedure(matchAll)},
+	}
+}
+
+// Exact returns a MethodMatcher matching exactly the given procedures
+func Exact(procedures ...string) *MethodMatcher {
+	return &MethodMatcher{
+		includes: []specMatcher{byProcedure(matchExact(procedures...))},
+	}
+}
+
+// AllExcept returns a MethodMatcher matching every procedure except exactly the given procedures
+func AllExcept(procedures ...string) *MethodMatcher {
+	return &MethodMatcher{
+		excludes: []specMatcher{byProcedure(matchExact(procedures...))},
+	}
+}
+
+// Methods returns a MethodMatcher matching every method satisfying any of the given predicates
+func Methods(predicates ...MethodPredicate) *MethodMatcher {
+	return (&MethodMatcher{}).Include(predicates...)
+}
+
+// Include adds the given predicates as inclusions, returning the matcher to allow chaining. Inclusions never match
+// procedures without a schema
+func (m *MethodMatcher) Include(predicates ...MethodPredicate) *MethodMatcher {
+	for _, predicate := range predicates {
+		m.includes = append(m.includes, byDescriptor(predicate, false))
+	}
+	return m
+}
+
+// Exclude adds the given predicates as exclusions, returning the matcher to allow chaining. Exclusions always match
+// procedures without a schema, so any matcher with a predicate exclusion matches nothing when the schema is missing
+func (m *MethodMatcher) Exclude(predicates ...MethodPredicate) *MethodMatcher {
+	for _, predicate := range predicates {
+		m.excludes = append(m.excludes, byDescriptor(predicate, true))
+	}
+	return m
+}
+
+// Matches reports whether the given procedure is matched. Only the procedure name is available, so MethodPredicates
+// behave as if the schema were missing; prefer MatchesSpec when the connect.Spec is available
+func (m *MethodMatcher) Matches(procedure string) bool {
+	return m.MatchesSpec(connect.Spec{Procedure: procedure})
+}
+
+// MatchesSpec reports whether the procedure described by the given spec is matched
+func (m *MethodMatcher) MatchesSpec(spec connect.Spec) bool {
+	if m == nil {
+		return false
+	}
+
+	for _, exclude := range m.excludes {
+		if exclude(spec) {
+			return false
+		}
+	}
+
+	if len(m.includes) == 0 {
+		return len(m.excludes) > 0
+	}
+
+	for _, include := range m.includes {
+		if include(spec) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func byProcedure(match procedureMatcher) specMatcher {
+	return func(spec connect.Spec) bool {
+		return match(spec.Procedure)
+	}
+}
+
+// byDescriptor applies the predicate to the specs method descriptor, returning missingSchema when there is none
+func byDescriptor(predicate MethodPredicate, missingSchema bool) specMatcher {
+	return func(spec connect.Spec) bool {
+		md, ok := spec.Schema.(protoreflect.MethodDescriptor)
+		if !ok || md == nil {
+			return missingSchema
+		}
+		return predicate(md)
+	}
+}
+
+func compile(pattern string) (procedureMatcher, error) {
+	switch {
+	case pattern == Wildcard:
+		return matchAll, nil
+	case strings.HasPrefix(pattern, RegexPrefix):
+		re, err := regexp.Compile(strings.TrimPrefix(pattern, RegexPrefix))
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex pattern %q: %w", pattern, err)
+		}
+		return re.MatchString, nil
+	case strings.ContainsAny(pattern, "*?[\\"):
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid glob pattern %q: %w", pattern, err)
+		}
+		return func(procedure string) bool {
+			matched, _ := path.Match(pattern, procedure)
+			return matched
+		}, nil
+	default:
+		return matchExact(pattern), nil
+	}
+}
+
+func matchAll(string) bool {
+	return true
+}
+
+func matchExact(procedures ...string) procedureMatcher {
+	set := make(map[string]struct{}, len(procedures))
+	for _, procedure := range procedures {
+		set[procedure] = struct{}{}
+	}
+	return func(procedure string) bool {
+		_, ok := set[procedure]
+		return ok
+	}
+}
@@ -0,0 +1,136 @@
+package matcher
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMethodMatcher(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name     string
+		patterns []string
+		input    string
+		expected bool
+	}{
+		{
+			name:     "no patterns no match",
+			patterns: []string{},
+			input:    "/pkg.v1.Service/Get",
+			expected: false,
+		},
+		{
+			name:     "wildcard",
+			patterns: []string{"*"},
+			input:    "/pkg.v1.Service/Get",
+			expected: true,
+		},
+		{
+			name:     "exact match",
+			patterns: []string{"/pkg.v1.Service/Get"},
+			input:    "/pkg.v1.Service/Get",
+			expected: true,
+		},
+		{
+			name:     "exact no match",
+			patterns: []string{"/pkg.v1.Service/Get"},
+			input:    "/pkg.v1.Service/List",
+			expected: false,
+		},
+		{
+			name:     "service wildcard",
+			patterns: []string{"/pkg.v1.Service/*"},
+			input:    "/pkg.v1.Service/List",
+			expected: true,
+		},
+		{
+			name:     "service wildcard other service",
+			patterns: []string{"/pkg.v1.Service/*"},
+			input:    "/pkg.v1.Other/List",
+			expected: false,
+		},
+		{
+			name:     "glob",
+			patterns: []string{"/pkg.*/Get*"},
+			input:    "/pkg.v2.Service/GetThing",
+			expected: true,
+		},
+		{
+			name:     "regex",
+			patterns: []string{`re:^/pkg\.v[0-9]+\.Service/`},
+			input:    "/pkg.v12.Service/Get",
+			expected: true,
+		},
+		{
+			name:     "regex no match",
+			patterns: []string{`re:^/pkg\.v[0-9]+\.Service/`},
+			input:    "/pkg.vx.Service/Get",
+			expected: false,
+		},
+		{
+			name:     "only exclusions match everything else",
+			patterns: []string{"!/pkg.v1.Service/Get"},
+			input:    "/pkg.v1.Service/List",
+			expected: true,
+		},
+		{
+			name:     "only exclusions excluded",
+			patterns: []string{"!/pkg.v1.Service/Get"},
+			input:    "/pkg.v1.Service/Get",
+			expected: false,
+		},
+		{
+			name:     "exclusion takes precedence",
+			patterns: []string{"/pkg.v1.Service/*", "!/pkg.v1.Service/Health"},
+			input:    "/pkg.v1.Service/Health",
+			expected: false,
+		},
+		{
+			name:     "inclusion alongside exclusion",
+			patterns: []string{"/pkg.v1.Service/*", "!/pkg.v1.Service/Health"},
+			input:    "/pkg.v1.Service/Get",
+			expected: true,
+		},
+		{
+			name:     "inclusion alongside exclusion not included",
+			patterns: []string{"/pkg.v1.Service/*", "!/pkg.v1.Service/Health"},
+			input:    "/pkg.v1.Other/Get",
+			expected: false,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			m, err := New(tc.patterns...)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, m.Matches(tc.input))
+		})
+	}
+}
+
+func TestMethodMatcherInvalidPatterns(t *testing.T) {
+	t.Parallel()
+
+	_, err := New("re:(")
+	require.Error(t, err)
+
+	_, err = New("/pkg.v1.Service/[")
+	require.Error(t, err)
+}
+
+func TestFromMethodList(t *testing.T) {
+	t.Parallel()
+
+	require.False(t, FromMethodList("").Matches("a"))
+	require.True(t, FromMethodList("*").Matches("a"))
+	require.True(t, FromMethodList("a,b").Matches("b"))
+	require.False(t, FromMethodList("a,b").Matches("c"))
+	require.False(t, AllExcept("a", "b").Matches("a"))
+	require.True(t, AllExcept("a", "b").Matches("c"))
+
+	var m *MethodMatcher
+	require.False(t, m.Matches("a"))
+}
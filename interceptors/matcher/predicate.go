@@ -0,0 +1,77 @@
+package matcher
+
+import (
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+// MethodPredicate selects methods based on their protobuf descriptor, as found in connect.Spec.Schema
+type MethodPredicate func(md protoreflect.MethodDescriptor) bool
+
+// IdempotencyLevel matches methods declaring the given idempotency_level option
+func IdempotencyLevel(level descriptorpb.MethodOptions_IdempotencyLevel) MethodPredicate {
+	return func(md protoreflect.MethodDescriptor) bool {
+		opts := methodOptions(md)
+		return opts != nil && opts.GetIdempotencyLevel() == level
+	}
+}
+
+// Deprecated matches methods marked with the deprecated option
+func Deprecated() MethodPredicate {
+	return func(md protoreflect.MethodDescriptor) bool {
+		return methodOptions(md).GetDeprecated()
+	}
+}
+
+// HasExtension matches methods where the given custom method option extension is set, regardless of value
+func HasExtension(xt protoreflect.ExtensionType) MethodPredicate {
+	return func(md protoreflect.MethodDescriptor) bool {
+		opts := methodOptions(md)
+		return opts != nil && proto.HasExtension(opts, xt)
+	}
+}
+
+// ExtensionEquals matches methods where the given custom method option extension is set to value, such as
+// ExtensionEquals(ourco.E_Audit, true) for a method annotated with option (ourco.audit) = true
+func ExtensionEquals(xt protoreflect.ExtensionType, value any) MethodPredicate {
+	return func(md protoreflect.MethodDescriptor) bool {
+		opts := methodOptions(md)
+		if opts == nil || !proto.HasExtension(opts, xt) {
+			return false
+		}
+
+		got := proto.GetExtension(opts, xt)
+		if gotMsg, ok := got.(proto.Message); ok {
+			wantMsg, ok := value.(proto.Message)
+			return ok && proto.Equal(gotMsg, wantMsg)
+		}
+		return reflect.DeepEqual(got, value)
+	}
+}
+
+// Not matches methods that do not satisfy the given predicate
+func Not(predicate MethodPredicate) MethodPredicate {
+	return func(md protoreflect.MethodDescriptor) bool {
+		return !predicate(md)
+	}
+}
+
+// AllOf matches methods satisfying every given predicate
+func AllOf(predicates ...MethodPredicate) MethodPredicate {
+	return func(md protoreflect.MethodDescriptor) bool {
+		for _, predicate := range predicates {
+			if !predicate(md) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+func methodOptions(md protoreflect.MethodDescriptor) *descriptorpb.MethodOptions {
+	opts, _ := md.Options().(*descriptorpb.MethodOptions)
+	return opts
+}
@@ -0,0 +1,187 @@
+package matcher
+
+import (
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestMethodPredicates(t *testing.T) {
+	t.Parallel()
+
+	services, auditExt := testServices(t)
+
+	spec := func(method string) connect.Spec {
+		md := services.Methods().ByName(protoreflect.Name(method))
+		require.NotNil(t, md)
+		return connect.Spec{
+			Procedure: "/test.v1.TestService/" + method,
+			Schema:    md,
+		}
+	}
+
+	testData := []struct {
+		name     string
+		matcher  *MethodMatcher
+		input    string
+		expected bool
+	}{
+		{
+			name:     "idempotency level match",
+			matcher:  Methods(IdempotencyLevel(descriptorpb.MethodOptions_NO_SIDE_EFFECTS)),
+			input:    "Get",
+			expected: true,
+		},
+		{
+			name:     "idempotency level no match",
+			matcher:  Methods(IdempotencyLevel(descriptorpb.MethodOptions_NO_SIDE_EFFECTS)),
+			input:    "Update",
+			expected: false,
+		},
+		{
+			name:     "deprecated match",
+			matcher:  Methods(Deprecated()),
+			input:    "Old",
+			expected: true,
+		},
+		{
+			name:     "deprecated no match",
+			matcher:  Methods(Deprecated()),
+			input:    "Get",
+			expected: false,
+		},
+		{
+			name:     "extension set",
+			matcher:  Methods(HasExtension(auditExt)),
+			input:    "Update",
+			expected: true,
+		},
+		{
+			name:     "extension equals",
+			matcher:  Methods(ExtensionEquals(auditExt, true)),
+			input:    "Update",
+			expected: true,
+		},
+		{
+			name:     "extension not equal",
+			matcher:  Methods(ExtensionEquals(auditExt, false)),
+			input:    "Update",
+			expected: false,
+		},
+		{
+			name:     "extension unset",
+			matcher:  Methods(HasExtension(auditExt)),
+			input:    "Get",
+			expected: false,
+		},
+		{
+			name:     "pattern include with predicate exclude",
+			matcher:  MustNew("/test.v1.TestService/*").Exclude(Deprecated()),
+			input:    "Old",
+			expected: false,
+		},
+		{
+			name:     "all of",
+			matcher:  Methods(AllOf(Deprecated(), Not(HasExtension(auditExt)))),
+			input:    "Old",
+			expected: true,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, tc.matcher.MatchesSpec(spec(tc.input)))
+		})
+	}
+
+	t.Run("included predicates never match without schema", func(t *testing.T) {
+		t.Parallel()
+
+		require.False(t, Methods(Not(Deprecated())).Matches("/test.v1.TestService/Get"))
+	})
+
+	t.Run("excluded predicates fail closed without schema", func(t *testing.T) {
+		t.Parallel()
+
+		m := All().Exclude(HasExtension(auditExt))
+		require.True(t, m.MatchesSpec(spec("Get")))
+		require.False(t, m.MatchesSpec(spec("Update")))
+		require.False(t, m.MatchesSpec(connect.Spec{Procedure: "/test.v1.TestService/Get"}))
+		require.False(t, m.Matches("/test.v1.TestService/Get"))
+	})
+}
+
+// testServices builds a service descriptor with a variety of method options, along with a custom (test.v1.audit)
+// method option extension
+func testServices(t *testing.T) (protoreflect.ServiceDescriptor, protoreflect.ExtensionType) {
+	t.Helper()
+
+	extFile, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/ext.proto"),
+		Package:    proto.String("test.v1"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     proto.String("audit"),
+				Number:   proto.Int32(50000),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+				Extendee: proto.String(".google.protobuf.MethodOptions"),
+			},
+		},
+		Syntax: proto.String("proto3"),
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	auditExt := dynamicpb.NewExtensionType(extFile.Extensions().Get(0))
+
+	auditOpts := &descriptorpb.MethodOptions{}
+	proto.SetExtension(auditOpts, auditExt, true)
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/service.proto"),
+		Package:    proto.String("test.v1"),
+		Dependency: []string{"google/protobuf/empty.proto"},
+		Service: []*descriptorpb.ServiceDescriptorProto{
+			{
+				Name: proto.String("TestService"),
+				Method: []*descriptorpb.MethodDescriptorProto{
+					{
+						Name:       proto.String("Get"),
+						InputType:  proto.String(".google.protobuf.Empty"),
+						OutputType: proto.String(".google.protobuf.Empty"),
+						Options: &descriptorpb.MethodOptions{
+							IdempotencyLevel: descriptorpb.MethodOptions_NO_SIDE_EFFECTS.Enum(),
+						},
+					},
+					{
+						Name:       proto.String("Update"),
+						InputType:  proto.String(".google.protobuf.Empty"),
+						OutputType: proto.String(".google.protobuf.Empty"),
+						Options:    auditOpts,
+					},
+					{
+						Name:       proto.String("Old"),
+						InputType:  proto.String(".google.protobuf.Empty"),
+						OutputType: proto.String(".google.protobuf.Empty"),
+						Options: &descriptorpb.MethodOptions{
+							Deprecated: proto.Bool(true),
+						},
+					},
+				},
+			},
+		},
+		Syntax: proto.String("proto3"),
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	return file.Services().Get(0), auditExt
+}
@@ -0,0 +1,144 @@
+package payload
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	payloadObjectKey = "object"
+	// DefaultFlattenedKeyPrefix is the default prefix of FlattenedFormatter keys, keeping payload fields named like
+	// common log keys (error, msg, time, etc) from colliding with them
+	DefaultFlattenedKeyPrefix = "payload."
+)
+
+// Formatter renders a payload for the payload logging interceptors, returning the key/value pairs it should be
+// logged with
+type Formatter interface {
+	Format(msg proto.Message) ([]any, error)
+}
+
+var (
+	_ Formatter = (*ProtoJSONFormatter)(nil)
+	_ Formatter = (*ProtoTextFormatter)(nil)
+	_ Formatter = (*FlattenedFormatter)(nil)
+)
+
+// ProtoJSONFormatter logs the payload as a single protojson string under the "object" key
+type ProtoJSONFormatter struct {
+	Options protojson.MarshalOptions
+}
+
+func (p *ProtoJSONFormatter) Format(msg proto.Message) ([]any, error) {
+	out, err := p.Options.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling with protojson: %w", err)
+	}
+	return []any{payloadObjectKey, string(out)}, nil
+}
+
+// ProtoTextFormatter logs the payload as a single prototext string under the "object" key
+type ProtoTextFormatter struct {
+	Options prototext.MarshalOptions
+}
+
+func (p *ProtoTextFormatter) Format(msg proto.Message) ([]any, error) {
+	out, err := p.Options.Marshal(msg)
+	if err != nil {
+		return nil, fmt.Errorf("error marshalling with prototext: %w", err)
+	}
+	return []any{payloadObjectKey, string(out)}, nil
+}
+
+// FlattenedFormatter logs each populated scalar field of the payload as its own key/value pair, allowing log
+// aggregators to index individual fields. Keys are the dot separated field path, such as order.customer_id, with
+// repeated & map elements addressed as items[0] & labels[key]. Well known types (Timestamp, Duration, etc) are logged
+// as their protojson representation rather than being flattened further
+type FlattenedFormatter struct {
+	// KeyPrefix is the optional prefix prepended to every key, to avoid collisions with other log keys. If not given
+	// will default to DefaultFlattenedKeyPrefix
+	KeyPrefix string
+	// UseJSONNames uses the lowerCamelCase JSON name of each field instead of the proto name
+	UseJSONNames bool
+}
+
+func (f *FlattenedFormatter) Format(msg proto.Message) ([]any, error) {
+	prefix := f.KeyPrefix
+	if prefix == "" {
+		prefix = DefaultFlattenedKeyPrefix
+	}
+
+	keysAndValues := []any{}
+	if err := f.flatten(msg.ProtoReflect(), prefix, &keysAndValues); err != nil {
+		return nil, err
+	}
+	return keysAndValues, nil
+}
+
+func (f *FlattenedFormatter) flatten(m protoreflect.Message, prefix string, keysAndValues *[]any) error {
+	var err error
+	m.Range(func(fd protoreflect.FieldDescriptor, v protoreflect.Value) bool {
+		key := prefix + f.fieldName(fd)
+
+		switch {
+		case fd.IsList():
+			list := v.List()
+			for i := 0; i < list.Len(); i++ {
+				if err = f.flattenValue(fd, list.Get(i), fmt.Sprintf("%v[%v]", key, i), keysAndValues); err != nil {
+					return false
+				}
+			}
+		case fd.IsMap():
+			v.Map().Range(func(k protoreflect.MapKey, mv protoreflect.Value) bool {
+				err = f.flattenValue(fd.MapValue(), mv, fmt.Sprintf("%v[%v]", key, k.String()), keysAndValues)
+				return err == nil
+			})
+		default:
+			err = f.flattenValue(fd, v, key, keysAndValues)
+		}
+
+		return err == nil
+	})
+	return err
+}
+
+func (f *FlattenedFormatter) flattenValue(fd protoreflect.FieldDescriptor, v protoreflect.Value, key string, keysAndValues *[]any) error {
+	if fd.Message() != nil {
+		if strings.HasPrefix(string(fd.Message().FullName()), "google.protobuf.") {
+			out, err := protojson.Marshal(v.Message().Interface())
+			if err != nil {
+				return fmt.Errorf("error marshalling %v with protojson: %w", key, err)
+			}
+			*keysAndValues = append(*keysAndValues, key, string(out))
+			return nil
+		}
+		return f.flatten(v.Message(), key+".", keysAndValues)
+	}
+
+	switch fd.Kind() {
+	case protoreflect.EnumKind:
+		if ev := fd.Enum().Values().ByNumber(v.Enum()); ev != nil {
+			*keysAndValues = append(*keysAndValues, key, string(ev.Name()))
+		} else {
+			*keysAndValues = append(*keysAndValues, key, int32(v.Enum()))
+		}
+	case protoreflect.BytesKind:
+		*keysAndValues = append(*keysAndValues, key, base64.StdEncoding.EncodeToString(v.Bytes()))
+	default:
+		*keysAndValues = append(*keysAndValues, key, v.Interface())
+	}
+	return nil
+}
+
+func (f *FlattenedFormatter) fieldName(fd protoreflect.FieldDescriptor) string {
+	if f.UseJSONNames {
+		return fd.JSONName()
+	}
+	return string(fd.Name())
+}
@@ -0,0 +1,72 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestFlattenedFormatter(t *testing.T) {
+	t.Parallel()
+
+	outer, _ := redactTestDescriptors(t)
+
+	msg := dynamicpb.NewMessage(outer)
+	require.NoError(t, protojson.Unmarshal([]byte(`{
+		"secret": {"name": "bob", "pin": "1234", "codes": ["1", "2"]},
+		"secrets": [{"name": "a"}],
+		"byName": {"x": {"name": "c"}},
+		"inner": {"apiKey": "key"},
+		"any": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "wrapped"}
+	}`), msg))
+
+	toMap := func(keysAndValues []any) map[string]any {
+		require.Zero(t, len(keysAndValues)%2)
+		out := map[string]any{}
+		for i := 0; i < len(keysAndValues); i += 2 {
+			out[keysAndValues[i].(string)] = keysAndValues[i+1]
+		}
+		return out
+	}
+
+	t.Run("proto names", func(t *testing.T) {
+		t.Parallel()
+
+		formatted, err := (&FlattenedFormatter{KeyPrefix: "payload."}).Format(msg)
+		require.NoError(t, err)
+
+		// protojson output whitespace is unstable, so compare the well known type separately
+		fields := toMap(formatted)
+		require.JSONEq(t, `{"@type":"type.googleapis.com/google.protobuf.StringValue","value":"wrapped"}`, fields["payload.any"].(string))
+		delete(fields, "payload.any")
+
+		require.Equal(t, map[string]any{
+			"payload.secret.name":     "bob",
+			"payload.secret.pin":      int64(1234),
+			"payload.secret.codes[0]": "1",
+			"payload.secret.codes[1]": "2",
+			"payload.secrets[0].name": "a",
+			"payload.by_name[x].name": "c",
+			"payload.inner.api_key":   "key",
+		}, fields)
+	})
+
+	t.Run("json names", func(t *testing.T) {
+		t.Parallel()
+
+		formatted, err := (&FlattenedFormatter{KeyPrefix: "msg_", UseJSONNames: true}).Format(msg)
+		require.NoError(t, err)
+		require.Contains(t, toMap(formatted), "msg_byName[x].name")
+		require.Contains(t, toMap(formatted), "msg_inner.apiKey")
+	})
+
+	t.Run("default prefix", func(t *testing.T) {
+		t.Parallel()
+
+		formatted, err := (&FlattenedFormatter{}).Format(msg)
+		require.NoError(t, err)
+		require.Contains(t, toMap(formatted), DefaultFlattenedKeyPrefix+"secret.name")
+	})
+}
@@ -0,0 +1,119 @@
+// Package payload provides the redaction, truncation, formatting & sinks shared by the server & client payload logging
+// interceptors
+package payload
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/go-logr/logr"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+type LoggerConfig struct {
+	// Pretty prints payloads as human-readable indented objects. If no Sink is given, payloads are printed to stdout
+	// in place of the logger
+	Pretty bool
+	// RedactFields optionally lists fields to redact, keyed by message full name (e.g. pkg.v1.LoginRequest) with field
+	// paths relative to that message (e.g. password, or credentials.token). Fields marked with the standard debug_redact
+	// option are always redacted
+	RedactFields map[string][]string
+	// RedactExtension is an optional custom field option extension marking fields to redact
+	RedactExtension protoreflect.ExtensionType
+	// RedactionPlaceholder is the optional value redacted fields are replaced with. If not given will default to
+	// DefaultRedactionPlaceholder
+	RedactionPlaceholder string
+	// MaxPayloadSize optionally limits the total size in bytes of the formatted payload, counting both keys & values.
+	// Once exceeded, string values are cut off and annotated with the number of bytes elided, and pairs that do not fit
+	// at all are dropped, with the number dropped logged under the "elided-pairs" key
+	MaxPayloadSize int
+	// MaxStringLength optionally limits the length in bytes of each string field
+	MaxStringLength int
+	// MaxBytesLength optionally limits the length of each bytes field
+	MaxBytesLength int
+	// MaxRepeatedElements optionally limits the number of elements logged for each repeated field
+	MaxRepeatedElements int
+	// Formatter optionally controls how payloads are rendered. If not given will default to a ProtoJSONFormatter,
+	// indented when Pretty is set
+	Formatter Formatter
+	// Sink optionally receives payloads in place of the logger
+	Sink Sink
+}
+
+// NewLogger returns a Logger applying the given redaction, truncation & formatting configuration
+func NewLogger(config LoggerConfig) *Logger {
+	formatter := config.Formatter
+	if formatter == nil {
+		formatter = &ProtoJSONFormatter{}
+		if config.Pretty {
+			formatter = &ProtoJSONFormatter{Options: protojson.MarshalOptions{Indent: "    "}}
+		}
+	}
+
+	sink := config.Sink
+	if sink == nil && config.Pretty {
+		sink = NewWriterSink(os.Stdout)
+	}
+
+	return &Logger{
+		redactor: newRedactor(config.RedactionPlaceholder, config.RedactFields, config.RedactExtension),
+		truncator: &truncator{
+			maxStringLength: config.MaxStringLength,
+			maxBytesLength:  config.MaxBytesLength,
+			maxRepeated:     config.MaxRepeatedElements,
+		},
+		maxPayloadSize: config.MaxPayloadSize,
+		formatter:      formatter,
+		sink:           sink,
+	}
+}
+
+// Logger redacts, truncates & formats payloads before logging them. It is shared by the server & client payload
+// logging interceptors, so both sides of a call are logged the same way
+type Logger struct {
+	redactor       *redactor
+	truncator      *truncator
+	maxPayloadSize int
+	formatter      Formatter
+	sink           Sink
+}
+
+// Log formats the given object and logs it, any additional keysAndValues are attached to the log line (or
+// written alongside the object to the sink). Objects that are not proto messages are ignored
+func (l *Logger) Log(log logr.Logger, obj any, objType string, keysAndValues ...any) {
+	objProto, ok := obj.(protoreflect.ProtoMessage)
+	if !ok {
+		return
+	}
+
+	payload := l.redactor.redact(objProto)
+	if notes := l.truncator.truncate(payload); len(notes) > 0 {
+		keysAndValues = append(keysAndValues, "truncated-fields", notes)
+	}
+
+	formatted, err := l.formatter.Format(payload)
+	if err != nil {
+		log.Error(err, fmt.Sprintf("unable to format object, cannot log %v", objType))
+		return
+	}
+	formatted, dropped := truncatePairs(formatted, l.maxPayloadSize)
+	if dropped > 0 {
+		keysAndValues = append(keysAndValues, "elided-pairs", dropped)
+	}
+
+	if l.sink != nil {
+		record := Record{
+			Time:          time.Now(),
+			Message:       objType,
+			Payload:       formatted,
+			KeysAndValues: keysAndValues,
+		}
+		if err := l.sink.WriteRecord(record); err != nil {
+			log.Error(err, fmt.Sprintf("unable to write %v to payload sink", objType))
+		}
+	} else {
+		log.Info(objType, append(formatted, keysAndValues...)...)
+	}
+}
@@ -0,0 +1,71 @@
+package payload
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestLoggerTotalBudget(t *testing.T) {
+	t.Parallel()
+
+	outer, _ := redactTestDescriptors(t)
+	msg := dynamicpb.NewMessage(outer)
+	require.NoError(t, protojson.Unmarshal([]byte(`{
+		"secrets": [{"name": "a"}, {"name": "b"}, {"name": "c"}, {"name": "d"}, {"name": "e"}, {"name": "f"}]
+	}`), msg))
+
+	var line map[string]any
+	log := funcr.NewJSON(func(obj string) {
+		line = map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(obj), &line))
+	}, funcr.Options{})
+
+	logger := NewLogger(LoggerConfig{
+		Formatter:      &FlattenedFormatter{},
+		MaxPayloadSize: 60,
+	})
+	logger.Log(log, msg, "request object")
+
+	size := 0
+	for key, value := range line {
+		if strings.HasPrefix(key, DefaultFlattenedKeyPrefix) {
+			size += len(key) + len(fmt.Sprint(value))
+		}
+	}
+	require.LessOrEqual(t, size, 60)
+	require.Equal(t, float64(4), line["elided-pairs"])
+}
+
+func TestLoggerTruncatedAny(t *testing.T) {
+	t.Parallel()
+
+	msg, err := anypb.New(wrapperspb.String("0123456789"))
+	require.NoError(t, err)
+
+	lines := []map[string]any{}
+	log := funcr.NewJSON(func(obj string) {
+		line := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(obj), &line))
+		lines = append(lines, line)
+	}, funcr.Options{})
+
+	logger := NewLogger(LoggerConfig{
+		MaxStringLength: 4,
+		MaxBytesLength:  4,
+	})
+	logger.Log(log, msg, "request object")
+
+	require.Len(t, lines, 1)
+	require.Equal(t, "request object", lines[0]["msg"])
+	require.Nil(t, lines[0]["error"])
+	require.Equal(t, []any{"value: 6 bytes elided"}, lines[0]["truncated-fields"])
+}
@@ -0,0 +1,189 @@
+package payload
+
+import (
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+const (
+	DefaultRedactionPlaceholder = "[REDACTED]"
+)
+
+// redactor replaces sensitive fields of a message before it is logged. A field is redacted if it is marked with the
+// standard debug_redact option, if it is marked with the configured custom extension, or if its path is listed for
+// the containing message type
+type redactor struct {
+	placeholder string
+	// fields maps a message full name to field paths relative to that message, such as "credentials.password"
+	fields    map[protoreflect.FullName][]string
+	extension protoreflect.ExtensionType
+}
+
+func newRedactor(placeholder string, fields map[string][]string, extension protoreflect.ExtensionType) *redactor {
+	if placeholder == "" {
+		placeholder = DefaultRedactionPlaceholder
+	}
+
+	byName := make(map[protoreflect.FullName][]string, len(fields))
+	for name, paths := range fields {
+		byName[protoreflect.FullName(name)] = paths
+	}
+
+	return &redactor{
+		placeholder: placeholder,
+		fields:      byName,
+		extension:   extension,
+	}
+}
+
+// redact returns a copy of the message with all sensitive fields redacted, leaving the original untouched
+func (r *redactor) redact(msg proto.Message) proto.Message {
+	clone := proto.Clone(msg)
+	r.redactMessage(clone.ProtoReflect(), nil)
+	return clone
+}
+
+func (r *redactor) redactMessage(m protoreflect.Message, inherited []string) {
+	if m.Descriptor().FullName() == "google.protobuf.Any" {
+		r.redactAny(m)
+		return
+	}
+
+	paths := append(append([]string{}, r.fields[m.Descriptor().FullName()]...), inherited...)
+
+	// Collect populated fields up front, as mutating a message during Range is not supported
+	fields := []protoreflect.FieldDescriptor{}
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	for _, fd := range fields {
+		if r.shouldRedact(fd, paths) {
+			r.redactField(m, fd)
+			continue
+		}
+
+		child := childPaths(paths, fd.Name())
+		switch {
+		case fd.IsList() && fd.Message() != nil:
+			list := m.Mutable(fd).List()
+			for i := 0; i < list.Len(); i++ {
+				r.redactMessage(list.Get(i).Message(), child)
+			}
+		case fd.IsMap() && fd.MapValue().Message() != nil:
+			m.Mutable(fd).Map().Range(func(_ protoreflect.MapKey, v protoreflect.Value) bool {
+				r.redactMessage(v.Message(), child)
+				return true
+			})
+		case !fd.IsList() && !fd.IsMap() && fd.Message() != nil:
+			r.redactMessage(m.Mutable(fd).Message(), child)
+		}
+	}
+}
+
+// redactAny redacts the message packed inside an Any, provided its type can be resolved from the global registry
+func (r *redactor) redactAny(m protoreflect.Message) {
+	anyMsg := &anypb.Any{}
+	proto.Merge(anyMsg, m.Interface())
+
+	inner, err := anyMsg.UnmarshalNew()
+	if err != nil {
+		return
+	}
+
+	r.redactMessage(inner.ProtoReflect(), nil)
+
+	value, err := proto.MarshalOptions{Deterministic: true}.Marshal(inner)
+	if err != nil {
+		return
+	}
+
+	fd := m.Descriptor().Fields().ByName("value")
+	m.Set(fd, protoreflect.ValueOfBytes(value))
+}
+
+func (r *redactor) shouldRedact(fd protoreflect.FieldDescriptor, paths []string) bool {
+	for _, path := range paths {
+		if path == string(fd.Name()) {
+			return true
+		}
+	}
+
+	opts, ok := fd.Options().(*descriptorpb.FieldOptions)
+	if !ok || opts == nil {
+		return false
+	}
+
+	if opts.GetDebugRedact() {
+		return true
+	}
+
+	if r.extension != nil && proto.HasExtension(opts, r.extension) {
+		if marked, ok := proto.GetExtension(opts, r.extension).(bool); ok {
+			return marked
+		}
+		return true
+	}
+
+	return false
+}
+
+// redactField replaces a string or bytes field (or the elements of a repeated/map field of those kinds) with the
+// placeholder. Fields of other kinds cannot hold the placeholder, so are cleared instead
+func (r *redactor) redactField(m protoreflect.Message, fd protoreflect.FieldDescriptor) {
+	placeholder, ok := r.placeholderFor(fd)
+	if !ok {
+		m.Clear(fd)
+		return
+	}
+
+	switch {
+	case fd.IsList():
+		list := m.Mutable(fd).List()
+		for i := 0; i < list.Len(); i++ {
+			list.Set(i, placeholder)
+		}
+	case fd.IsMap():
+		mapVal := m.Mutable(fd).Map()
+		mapVal.Range(func(k protoreflect.MapKey, _ protoreflect.Value) bool {
+			mapVal.Set(k, placeholder)
+			return true
+		})
+	default:
+		m.Set(fd, placeholder)
+	}
+}
+
+func (r *redactor) placeholderFor(fd protoreflect.FieldDescriptor) (protoreflect.Value, bool) {
+	kind := fd.Kind()
+	if fd.IsMap() {
+		kind = fd.MapValue().Kind()
+	}
+
+	switch kind {
+	case protoreflect.StringKind:
+		return protoreflect.ValueOfString(r.placeholder), true
+	case protoreflect.BytesKind:
+		return protoreflect.ValueOfBytes([]byte(r.placeholder)), true
+	default:
+		return protoreflect.Value{}, false
+	}
+}
+
+// childPaths returns the remainder of each path nested under the given field name
+func childPaths(paths []string, name protoreflect.Name) []string {
+	prefix := string(name) + "."
+
+	child := []string{}
+	for _, path := range paths {
+		if rest, ok := strings.CutPrefix(path, prefix); ok {
+			child = append(child, rest)
+		}
+	}
+	return child
+}
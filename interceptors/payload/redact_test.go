@@ -0,0 +1,151 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/reflect/protoregistry"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+	_ "google.golang.org/protobuf/types/known/anypb"
+	_ "google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestRedactor(t *testing.T) {
+	t.Parallel()
+
+	outer, sensitiveExt := redactTestDescriptors(t)
+
+	input := `{
+		"secret": {"password": "hunter2", "token": "abc", "name": "bob", "pin": "1234", "codes": ["1", "2"]},
+		"secrets": [{"password": "p1", "name": "a"}, {"token": "t2", "name": "b"}],
+		"byName": {"x": {"password": "p3", "name": "c"}},
+		"inner": {"apiKey": "key", "note": "hello"},
+		"any": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "wrapped"}
+	}`
+
+	expected := `{
+		"secret": {"password": "[REDACTED]", "token": "[REDACTED]", "name": "bob", "codes": ["[REDACTED]", "[REDACTED]"]},
+		"secrets": [{"password": "[REDACTED]", "name": "a"}, {"token": "[REDACTED]", "name": "b"}],
+		"byName": {"x": {"password": "[REDACTED]", "name": "c"}},
+		"inner": {"apiKey": "[REDACTED]", "note": "hello"},
+		"any": {"@type": "type.googleapis.com/google.protobuf.StringValue", "value": "[REDACTED]"}
+	}`
+
+	msg := dynamicpb.NewMessage(outer)
+	require.NoError(t, protojson.Unmarshal([]byte(input), msg))
+	original, err := protojson.Marshal(msg)
+	require.NoError(t, err)
+
+	r := newRedactor("", map[string][]string{
+		"test.v1.Outer":               {"inner.api_key"},
+		"google.protobuf.StringValue": {"value"},
+	}, sensitiveExt)
+
+	out, err := protojson.Marshal(r.redact(msg))
+	require.NoError(t, err)
+	require.JSONEq(t, expected, string(out))
+
+	after, err := protojson.Marshal(msg)
+	require.NoError(t, err)
+	require.JSONEq(t, string(original), string(after), "original message should not be modified")
+}
+
+// redactTestDescriptors builds an Outer message exercising nested, repeated, map & Any fields, along with a custom
+// (test.v1.sensitive) field option extension
+func redactTestDescriptors(t *testing.T) (protoreflect.MessageDescriptor, protoreflect.ExtensionType) {
+	t.Helper()
+
+	field := func(name string, number int32, typ descriptorpb.FieldDescriptorProto_Type, typeName string, opts *descriptorpb.FieldOptions) *descriptorpb.FieldDescriptorProto {
+		fd := &descriptorpb.FieldDescriptorProto{
+			Name:    proto.String(name),
+			Number:  proto.Int32(number),
+			Label:   descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+			Type:    typ.Enum(),
+			Options: opts,
+		}
+		if typeName != "" {
+			fd.TypeName = proto.String(typeName)
+		}
+		return fd
+	}
+	repeated := func(fd *descriptorpb.FieldDescriptorProto) *descriptorpb.FieldDescriptorProto {
+		fd.Label = descriptorpb.FieldDescriptorProto_LABEL_REPEATED.Enum()
+		return fd
+	}
+	redacted := &descriptorpb.FieldOptions{DebugRedact: proto.Bool(true)}
+
+	extFile, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/redact_ext.proto"),
+		Package:    proto.String("test.v1"),
+		Dependency: []string{"google/protobuf/descriptor.proto"},
+		Extension: []*descriptorpb.FieldDescriptorProto{
+			{
+				Name:     proto.String("sensitive"),
+				Number:   proto.Int32(50001),
+				Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+				Type:     descriptorpb.FieldDescriptorProto_TYPE_BOOL.Enum(),
+				Extendee: proto.String(".google.protobuf.FieldOptions"),
+			},
+		},
+		Syntax: proto.String("proto3"),
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+	sensitiveExt := dynamicpb.NewExtensionType(extFile.Extensions().Get(0))
+
+	sensitive := &descriptorpb.FieldOptions{}
+	proto.SetExtension(sensitive, sensitiveExt, true)
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:       proto.String("test/v1/redact.proto"),
+		Package:    proto.String("test.v1"),
+		Dependency: []string{"google/protobuf/any.proto"},
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Secret"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("password", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", redacted),
+					field("token", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", sensitive),
+					field("name", 3, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", nil),
+					field("pin", 4, descriptorpb.FieldDescriptorProto_TYPE_INT64, "", redacted),
+					repeated(field("codes", 5, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", redacted)),
+				},
+			},
+			{
+				Name: proto.String("Inner"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("api_key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", nil),
+					field("note", 2, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", nil),
+				},
+			},
+			{
+				Name: proto.String("Outer"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					field("secret", 1, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.v1.Secret", nil),
+					repeated(field("secrets", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.v1.Secret", nil)),
+					repeated(field("by_name", 3, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.v1.Outer.ByNameEntry", nil)),
+					field("inner", 4, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.v1.Inner", nil),
+					field("any", 5, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".google.protobuf.Any", nil),
+				},
+				NestedType: []*descriptorpb.DescriptorProto{
+					{
+						Name: proto.String("ByNameEntry"),
+						Field: []*descriptorpb.FieldDescriptorProto{
+							field("key", 1, descriptorpb.FieldDescriptorProto_TYPE_STRING, "", nil),
+							field("value", 2, descriptorpb.FieldDescriptorProto_TYPE_MESSAGE, ".test.v1.Secret", nil),
+						},
+						Options: &descriptorpb.MessageOptions{MapEntry: proto.Bool(true)},
+					},
+				},
+			},
+		},
+		Syntax: proto.String("proto3"),
+	}, protoregistry.GlobalFiles)
+	require.NoError(t, err)
+
+	return file.Messages().ByName("Outer"), sensitiveExt
+}
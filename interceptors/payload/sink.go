@@ -0,0 +1,288 @@
+package payload
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a single payload captured by the payload logging interceptors
+type Record struct {
+	Time time.Time
+	// Message describes the payload, such as "request object"
+	Message string
+	// Payload is the formatted payload key/value pairs, as returned from the Formatter
+	Payload []any
+	// KeysAndValues is the additional context of the payload, such as stream direction & index
+	KeysAndValues []any
+}
+
+// Sink receives captured payloads in place of the logr pipeline. Implementations must be safe for concurrent
+// use
+type Sink interface {
+	WriteRecord(record Record) error
+}
+
+var (
+	_ Sink = (*WriterSink)(nil)
+	_ Sink = (*JSONLinesSink)(nil)
+)
+
+// NewWriterSink returns a sink writing payloads in a human-readable form to the given writer
+func NewWriterSink(w io.Writer) *WriterSink {
+	return &WriterSink{
+		w: w,
+	}
+}
+
+type WriterSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func (w *WriterSink) WriteRecord(record Record) error {
+	header := ""
+	if len(record.KeysAndValues) > 0 {
+		header = fmt.Sprintln(record.KeysAndValues...)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	_, err := fmt.Fprintln(w.w, "\n"+header+prettyPayload(record.Payload))
+	return err
+}
+
+// prettyPayload renders formatted key/value pairs for human consumption. Single object payloads are printed as is,
+// anything else is printed as one key: value pair per line
+func prettyPayload(formatted []any) string {
+	if len(formatted) == 2 && formatted[0] == payloadObjectKey {
+		return fmt.Sprint(formatted[1])
+	}
+
+	lines := []string{}
+	for i := 0; i+1 < len(formatted); i += 2 {
+		lines = append(lines, fmt.Sprintf("%v: %v", formatted[i], formatted[i+1]))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// NewJSONLinesSink returns a sink writing each payload as a single line JSON object to the given writer
+func NewJSONLinesSink(w io.Writer) *JSONLinesSink {
+	return &JSONLinesSink{
+		w: w,
+	}
+}
+
+// NewJSONLinesFileSink returns a JSONLinesSink appending to the file at path, creating it if required. The returned
+// sink should be closed once no longer in use
+func NewJSONLinesFileSink(path string) (*JSONLinesSink, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening payload sink file: %w", err)
+	}
+
+	return &JSONLinesSink{
+		w:      f,
+		closer: f,
+	}, nil
+}
+
+type JSONLinesSink struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+func (j *JSONLinesSink) WriteRecord(record Record) error {
+	obj := map[string]any{
+		"time": record.Time.UTC().Format(time.RFC3339Nano),
+		"msg":  record.Message,
+	}
+	for _, keysAndValues := range [][]any{record.Payload, record.KeysAndValues} {
+		for i := 0; i+1 < len(keysAndValues); i += 2 {
+			obj[fmt.Sprint(keysAndValues[i])] = keysAndValues[i+1]
+		}
+	}
+
+	line, err := json.Marshal(obj)
+	if err != nil {
+		return fmt.Errorf("error marshalling payload record: %w", err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err = j.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying file, if the sink was created with NewJSONLinesFileSink or the writer is an io.Closer
+func (j *JSONLinesSink) Close() error {
+	if j.closer != nil {
+		return j.closer.Close()
+	}
+	if closer, ok := j.w.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// rotatedFileTimeFormat is the timestamp suffix of rotated files
+const rotatedFileTimeFormat = "20060102T150405.000000000"
+
+type RotatingFileConfig struct {
+	// Path is the path of the active file. Rotated files are renamed to Path with a timestamp suffix
+	Path string
+	// MaxSize is the optional size in bytes after which the file is rotated
+	MaxSize int64
+	// MaxAge is the optional age after which the file is rotated
+	MaxAge time.Duration
+	// MaxBackups is the optional number of rotated files to keep, older files are removed. If not given all rotated
+	// files are kept
+	MaxBackups int
+}
+
+// NewRotatingFile opens a writer appending to the configured path, rotating the file once it exceeds the configured
+// size or age. Intended to be used as the writer of a WriterSink or JSONLinesSink
+func NewRotatingFile(config RotatingFileConfig) (*RotatingFile, error) {
+	r := &RotatingFile{
+		config: config,
+		now:    time.Now,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+var _ io.WriteCloser = (*RotatingFile)(nil)
+
+type RotatingFile struct {
+	config RotatingFileConfig
+	now    func() time.Time
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+func (r *RotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// A failed rotation is reported, but the write still goes to the reopened active file rather than being lost
+	var rotateErr error
+	if r.shouldRotate(int64(len(p))) {
+		rotateErr = r.rotate()
+	}
+
+	n, err := r.file.Write(p)
+	r.size += int64(n)
+	return n, errors.Join(rotateErr, err)
+}
+
+func (r *RotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.file.Close()
+}
+
+func (r *RotatingFile) shouldRotate(incoming int64) bool {
+	// Never rotate an empty file, otherwise a single write larger than MaxSize would rotate forever
+	if r.size == 0 {
+		return false
+	}
+	if r.config.MaxSize > 0 && r.size+incoming > r.config.MaxSize {
+		return true
+	}
+	if r.config.MaxAge > 0 && r.now().Sub(r.openedAt) >= r.config.MaxAge {
+		return true
+	}
+	return false
+}
+
+func (r *RotatingFile) open() error {
+	f, err := os.OpenFile(r.config.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return fmt.Errorf("error opening rotating file: %w", err)
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		_ = f.Close()
+		return fmt.Errorf("error inspecting rotating file: %w", err)
+	}
+
+	r.file = f
+	r.size = info.Size()
+	r.openedAt = r.now()
+	return nil
+}
+
+func (r *RotatingFile) rotate() (err error) {
+	// Always reopen the active file, even if rotation fails part way, so that later writes do not fail on a closed file
+	defer func() {
+		if openErr := r.open(); openErr != nil {
+			err = errors.Join(err, openErr)
+		}
+	}()
+
+	if err := r.file.Close(); err != nil && !errors.Is(err, os.ErrClosed) {
+		return fmt.Errorf("error closing rotating file: %w", err)
+	}
+
+	rotated := r.config.Path + "." + r.now().UTC().Format(rotatedFileTimeFormat)
+	if err := os.Rename(r.config.Path, rotated); err != nil {
+		return fmt.Errorf("error rotating file: %w", err)
+	}
+
+	return r.prune()
+}
+
+// prune removes the oldest rotated files beyond MaxBackups. Only files named as rotate names them are considered, so
+// unrelated files sharing the prefix (e.g. payloads.log.lock) are left alone
+func (r *RotatingFile) prune() error {
+	if r.config.MaxBackups <= 0 {
+		return nil
+	}
+
+	matches, err := filepath.Glob(r.config.Path + ".*")
+	if err != nil {
+		return fmt.Errorf("error listing rotated files: %w", err)
+	}
+
+	backups := []string{}
+	prefix := r.config.Path + "."
+	for _, match := range matches {
+		suffix, ok := strings.CutPrefix(match, prefix)
+		if !ok {
+			continue
+		}
+		if _, err := time.Parse(rotatedFileTimeFormat, suffix); err == nil {
+			backups = append(backups, match)
+		}
+	}
+	if len(backups) <= r.config.MaxBackups {
+		return nil
+	}
+
+	// The timestamp suffix sorts lexically in chronological order
+	sort.Strings(backups)
+	for _, old := range backups[:len(backups)-r.config.MaxBackups] {
+		if err := os.Remove(old); err != nil {
+			return fmt.Errorf("error removing rotated file: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package payload
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestJSONLinesSink(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	sink := NewJSONLinesSink(buf)
+
+	require.NoError(t, sink.WriteRecord(Record{
+		Time:          time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Message:       "request object",
+		Payload:       []any{"object", `{"a":"b"}`},
+		KeysAndValues: []any{"direction", "receive", "index", 0},
+	}))
+	require.NoError(t, sink.WriteRecord(Record{
+		Time:    time.Date(2024, 1, 2, 3, 4, 6, 0, time.UTC),
+		Message: "response object",
+		Payload: []any{"order_id", "123"},
+	}))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+	require.JSONEq(t, `{"time":"2024-01-02T03:04:05Z","msg":"request object","object":"{\"a\":\"b\"}","direction":"receive","index":0}`, string(lines[0]))
+	require.JSONEq(t, `{"time":"2024-01-02T03:04:06Z","msg":"response object","order_id":"123"}`, string(lines[1]))
+}
+
+func TestRotatingFile(t *testing.T) {
+	t.Parallel()
+
+	listBackups := func(t *testing.T, path string) []string {
+		matches, err := filepath.Glob(path + ".*")
+		require.NoError(t, err)
+		return matches
+	}
+
+	t.Run("size", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "payloads.log")
+		f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: 10, MaxBackups: 2})
+		require.NoError(t, err)
+
+		now := time.Unix(0, 0)
+		f.now = func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+
+		for _, line := range []string{"aaaaaa\n", "bbbbbb\n", "cccccc\n", "dddddd\n"} {
+			_, err := f.Write([]byte(line))
+			require.NoError(t, err)
+		}
+		require.NoError(t, f.Close())
+
+		current, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "dddddd\n", string(current))
+
+		backups := listBackups(t, path)
+		require.Len(t, backups, 2)
+		oldest, err := os.ReadFile(backups[0])
+		require.NoError(t, err)
+		require.Equal(t, "bbbbbb\n", string(oldest))
+	})
+
+	t.Run("age", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "payloads.log")
+
+		now := time.Unix(0, 0)
+		f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxAge: time.Minute})
+		require.NoError(t, err)
+		f.now = func() time.Time { return now }
+		f.openedAt = now
+
+		_, err = f.Write([]byte("first\n"))
+		require.NoError(t, err)
+
+		now = now.Add(2 * time.Minute)
+		_, err = f.Write([]byte("second\n"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		current, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "second\n", string(current))
+		require.Len(t, listBackups(t, path), 1)
+	})
+
+	t.Run("unrelated files kept", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "payloads.log")
+		for _, name := range []string{path + ".lock", path + ".gz"} {
+			require.NoError(t, os.WriteFile(name, []byte("keep"), 0o644))
+		}
+
+		f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: 5, MaxBackups: 1})
+		require.NoError(t, err)
+
+		now := time.Unix(0, 0)
+		f.now = func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+
+		for _, line := range []string{"aaaa\n", "bbbb\n", "cccc\n"} {
+			_, err := f.Write([]byte(line))
+			require.NoError(t, err)
+		}
+		require.NoError(t, f.Close())
+
+		require.FileExists(t, path+".lock")
+		require.FileExists(t, path+".gz")
+		// The single kept backup, alongside the two unrelated files
+		require.Len(t, listBackups(t, path), 3)
+	})
+
+	t.Run("failed prune keeps writing", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "payloads.log")
+
+		// A non-empty directory named like a backup cannot be removed, failing the prune
+		stuck := path + "." + time.Unix(0, 0).UTC().Format(rotatedFileTimeFormat)
+		require.NoError(t, os.MkdirAll(filepath.Join(stuck, "child"), 0o755))
+
+		f, err := NewRotatingFile(RotatingFileConfig{Path: path, MaxSize: 5, MaxBackups: 1})
+		require.NoError(t, err)
+
+		now := time.Unix(0, 0)
+		f.now = func() time.Time {
+			now = now.Add(time.Second)
+			return now
+		}
+
+		_, err = f.Write([]byte("aaaa\n"))
+		require.NoError(t, err)
+		n, err := f.Write([]byte("bbbb\n"))
+		require.Error(t, err)
+		require.Equal(t, 5, n)
+
+		// Once the stuck backup is cleared, rotation & pruning recover
+		require.NoError(t, os.RemoveAll(stuck))
+		_, err = f.Write([]byte("cccc\n"))
+		require.NoError(t, err)
+		require.NoError(t, f.Close())
+
+		current, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "cccc\n", string(current))
+
+		backups := listBackups(t, path)
+		require.Len(t, backups, 1)
+		previous, err := os.ReadFile(backups[0])
+		require.NoError(t, err)
+		require.Equal(t, "bbbb\n", string(previous))
+	})
+}
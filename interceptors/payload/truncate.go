@@ -0,0 +1,189 @@
+package payload
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/known/anypb"
+)
+
+// truncator bounds the size of string, bytes & repeated fields of a message before it is logged. Each limit is
+// disabled when zero
+type truncator struct {
+	maxStringLength int
+	maxBytesLength  int
+	maxRepeated     int
+}
+
+func (t *truncator) enabled() bool {
+	return t.maxStringLength > 0 || t.maxBytesLength > 0 || t.maxRepeated > 0
+}
+
+// truncate shortens fields exceeding the configured limits in place, returning a note per truncated field describing
+// how much was elided. The message must already be a copy, such as the one returned from redactor.redact
+func (t *truncator) truncate(msg proto.Message) []string {
+	if !t.enabled() {
+		return nil
+	}
+
+	notes := []string{}
+	t.truncateMessage(msg.ProtoReflect(), "", &notes)
+	return notes
+}
+
+func (t *truncator) truncateMessage(m protoreflect.Message, path string, notes *[]string) {
+	// Truncating the type URL or packed bytes of an Any directly would leave it impossible to format
+	if m.Descriptor().FullName() == "google.protobuf.Any" {
+		t.truncateAny(m, path, notes)
+		return
+	}
+
+	fields := []protoreflect.FieldDescriptor{}
+	m.Range(func(fd protoreflect.FieldDescriptor, _ protoreflect.Value) bool {
+		fields = append(fields, fd)
+		return true
+	})
+
+	for _, fd := range fields {
+		fieldPath := string(fd.Name())
+		if path != "" {
+			fieldPath = path + "." + fieldPath
+		}
+
+		switch {
+		case fd.IsList():
+			list := m.Mutable(fd).List()
+			if t.maxRepeated > 0 && list.Len() > t.maxRepeated {
+				*notes = append(*notes, fmt.Sprintf("%v: %v elements elided", fieldPath, list.Len()-t.maxRepeated))
+				list.Truncate(t.maxRepeated)
+			}
+			for i := 0; i < list.Len(); i++ {
+				elemPath := fmt.Sprintf("%v[%v]", fieldPath, i)
+				if fd.Message() != nil {
+					t.truncateMessage(list.Get(i).Message(), elemPath, notes)
+				} else if v, ok := t.truncateScalar(fd.Kind(), list.Get(i), elemPath, notes); ok {
+					list.Set(i, v)
+				}
+			}
+		case fd.IsMap():
+			mapVal := m.Mutable(fd).Map()
+			mapVal.Range(func(k protoreflect.MapKey, v protoreflect.Value) bool {
+				entryPath := fmt.Sprintf("%v[%v]", fieldPath, k.String())
+				if fd.MapValue().Message() != nil {
+					t.truncateMessage(v.Message(), entryPath, notes)
+				} else if truncated, ok := t.truncateScalar(fd.MapValue().Kind(), v, entryPath, notes); ok {
+					mapVal.Set(k, truncated)
+				}
+				return true
+			})
+		case fd.Message() != nil:
+			t.truncateMessage(m.Mutable(fd).Message(), fieldPath, notes)
+		default:
+			if v, ok := t.truncateScalar(fd.Kind(), m.Get(fd), fieldPath, notes); ok {
+				m.Set(fd, v)
+			}
+		}
+	}
+}
+
+// truncateAny truncates the message packed inside an Any, provided its type can be resolved from the global registry.
+// Otherwise the Any is left untouched
+func (t *truncator) truncateAny(m protoreflect.Message, path string, notes *[]string) {
+	anyMsg := &anypb.Any{}
+	proto.Merge(anyMsg, m.Interface())
+
+	inner, err := anyMsg.UnmarshalNew()
+	if err != nil {
+		return
+	}
+
+	innerNotes := []string{}
+	t.truncateMessage(inner.ProtoReflect(), path, &innerNotes)
+	if len(innerNotes) == 0 {
+		return
+	}
+
+	value, err := proto.MarshalOptions{Deterministic: true}.Marshal(inner)
+	if err != nil {
+		return
+	}
+
+	fd := m.Descriptor().Fields().ByName("value")
+	m.Set(fd, protoreflect.ValueOfBytes(value))
+	*notes = append(*notes, innerNotes...)
+}
+
+// truncateScalar returns the truncated value, and true, if the value is a string or bytes exceeding its limit
+func (t *truncator) truncateScalar(kind protoreflect.Kind, v protoreflect.Value, path string, notes *[]string) (protoreflect.Value, bool) {
+	switch kind {
+	case protoreflect.StringKind:
+		str := v.String()
+		if t.maxStringLength <= 0 || len(str) <= t.maxStringLength {
+			return v, false
+		}
+
+		// Back off to a rune boundary so the result remains valid UTF-8
+		cut := t.maxStringLength
+		for cut > 0 && !utf8.RuneStart(str[cut]) {
+			cut--
+		}
+
+		*notes = append(*notes, fmt.Sprintf("%v: %v bytes elided", path, len(str)-cut))
+		return protoreflect.ValueOfString(str[:cut]), true
+	case protoreflect.BytesKind:
+		b := v.Bytes()
+		if t.maxBytesLength <= 0 || len(b) <= t.maxBytesLength {
+			return v, false
+		}
+
+		*notes = append(*notes, fmt.Sprintf("%v: %v bytes elided", path, len(b)-t.maxBytesLength))
+		return protoreflect.ValueOfBytes(b[:t.maxBytesLength]), true
+	default:
+		return v, false
+	}
+}
+
+// truncateOutput bounds the total size of a marshaled payload, annotating the output with how much was elided
+func truncateOutput(out []byte, maxSize int) []byte {
+	if maxSize <= 0 || len(out) <= maxSize {
+		return out
+	}
+
+	return append(out[:maxSize:maxSize], fmt.Sprintf("... [%v bytes elided]", len(out)-maxSize)...)
+}
+
+// truncatePairs bounds the total size of formatted key/value pairs, counting both keys & values. Once the budget runs
+// out, a string value is cut off with truncateOutput if its key still fits, and every remaining pair is dropped. The
+// number of dropped pairs is returned
+func truncatePairs(formatted []any, maxSize int) ([]any, int) {
+	if maxSize <= 0 {
+		return formatted, 0
+	}
+
+	kept := make([]any, 0, len(formatted))
+	remaining := maxSize
+	dropped := 0
+	for i := 0; i+1 < len(formatted); i += 2 {
+		key := fmt.Sprint(formatted[i])
+		str, isString := formatted[i+1].(string)
+		if !isString {
+			str = fmt.Sprint(formatted[i+1])
+		}
+
+		switch size := len(key) + len(str); {
+		case size <= remaining:
+			kept = append(kept, formatted[i], formatted[i+1])
+			remaining -= size
+		case isString && remaining > len(key):
+			kept = append(kept, formatted[i], string(truncateOutput([]byte(str), remaining-len(key))))
+			remaining = 0
+		default:
+			dropped++
+			remaining = 0
+		}
+	}
+
+	return kept, dropped
+}
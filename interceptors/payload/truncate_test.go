@@ -0,0 +1,157 @@
+package payload
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/dynamicpb"
+	"google.golang.org/protobuf/types/known/anypb"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestTruncator(t *testing.T) {
+	t.Parallel()
+
+	outer, _ := redactTestDescriptors(t)
+
+	t.Run("strings and repeated", func(t *testing.T) {
+		t.Parallel()
+
+		input := `{
+			"secret": {"name": "abcdefgh", "codes": ["1", "2", "3"]},
+			"secrets": [{"name": "a"}, {"name": "b"}, {"name": "c"}, {"name": "d"}],
+			"byName": {"x": {"name": "hééé"}}
+		}`
+		expected := `{
+			"secret": {"name": "abcd", "codes": ["1", "2"]},
+			"secrets": [{"name": "a"}, {"name": "b"}],
+			"byName": {"x": {"name": "hé"}}
+		}`
+
+		msg := dynamicpb.NewMessage(outer)
+		require.NoError(t, protojson.Unmarshal([]byte(input), msg))
+
+		tr := &truncator{maxStringLength: 4, maxRepeated: 2}
+		notes := tr.truncate(msg)
+
+		out, err := protojson.Marshal(msg)
+		require.NoError(t, err)
+		require.JSONEq(t, expected, string(out))
+		require.ElementsMatch(t, []string{
+			"secret.name: 4 bytes elided",
+			"secret.codes: 1 elements elided",
+			"secrets: 2 elements elided",
+			"by_name[x].name: 4 bytes elided",
+		}, notes)
+	})
+
+	t.Run("bytes", func(t *testing.T) {
+		t.Parallel()
+
+		msg := wrapperspb.Bytes([]byte("0123456789"))
+
+		tr := &truncator{maxBytesLength: 3}
+		notes := tr.truncate(msg)
+
+		require.Equal(t, []byte("012"), msg.GetValue())
+		require.Equal(t, []string{"value: 7 bytes elided"}, notes)
+	})
+
+	t.Run("any", func(t *testing.T) {
+		t.Parallel()
+
+		msg, err := anypb.New(wrapperspb.String("0123456789"))
+		require.NoError(t, err)
+		typeURL := msg.GetTypeUrl()
+
+		tr := &truncator{maxStringLength: 4, maxBytesLength: 4}
+		notes := tr.truncate(msg)
+
+		require.Equal(t, typeURL, msg.GetTypeUrl())
+		inner, err := msg.UnmarshalNew()
+		require.NoError(t, err)
+		require.Equal(t, "0123", inner.(*wrapperspb.StringValue).GetValue())
+		require.Equal(t, []string{"value: 6 bytes elided"}, notes)
+	})
+
+	t.Run("unresolvable any", func(t *testing.T) {
+		t.Parallel()
+
+		msg := &anypb.Any{TypeUrl: "type.googleapis.com/unknown.v1.Type", Value: []byte("0123456789")}
+
+		tr := &truncator{maxStringLength: 4, maxBytesLength: 4}
+		require.Empty(t, tr.truncate(msg))
+		require.Equal(t, "type.googleapis.com/unknown.v1.Type", msg.GetTypeUrl())
+		require.Equal(t, []byte("0123456789"), msg.GetValue())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		t.Parallel()
+
+		msg := wrapperspb.String("0123456789")
+
+		tr := &truncator{}
+		require.Empty(t, tr.truncate(msg))
+		require.Equal(t, "0123456789", msg.GetValue())
+	})
+}
+
+func TestTruncateOutput(t *testing.T) {
+	t.Parallel()
+
+	require.Equal(t, "0123", string(truncateOutput([]byte("0123"), 0)))
+	require.Equal(t, "0123", string(truncateOutput([]byte("0123"), 4)))
+	require.Equal(t, "01... [2 bytes elided]", string(truncateOutput([]byte("0123"), 2)))
+}
+
+func TestTruncatePairs(t *testing.T) {
+	t.Parallel()
+
+	formatted := []any{"a", "0123", "b", 4567, "c", "89"}
+
+	testData := []struct {
+		name            string
+		maxSize         int
+		expected        []any
+		expectedDropped int
+	}{
+		{
+			name:     "unlimited",
+			maxSize:  0,
+			expected: formatted,
+		},
+		{
+			name:     "fits",
+			maxSize:  15,
+			expected: formatted,
+		},
+		{
+			name:            "string value cut off",
+			maxSize:         3,
+			expected:        []any{"a", "01... [2 bytes elided]"},
+			expectedDropped: 2,
+		},
+		{
+			name:            "non-string value dropped",
+			maxSize:         7,
+			expected:        []any{"a", "0123"},
+			expectedDropped: 2,
+		},
+		{
+			name:            "everything dropped",
+			maxSize:         1,
+			expected:        []any{},
+			expectedDropped: 3,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			kept, dropped := truncatePairs(formatted, tc.maxSize)
+			require.Equal(t, tc.expected, kept)
+			require.Equal(t, tc.expectedDropped, dropped)
+		})
+	}
+}
@@ -2,24 +2,78 @@ package server
 
 import (
 	"context"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
 
 	"connectrpc.com/connect"
 	"github.com/go-logr/logr"
 	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
-	"github.com/oklog/ulid/v2"
 )
 
 type ContextLoggerInterceptorConfig struct {
 	// RootLogger is the logger that all request level loggers will inherit from
 	RootLogger logr.Logger
-	// NoAttachRequestID indicates that a request ulid should not be generated and attached to the logger
+	// NoAttachRequestID indicates that a request ID should not be read, generated, or attached to the logger or
+	// context
 	NoAttachRequestID bool
+	// RequestIDHeader is the optional inbound header to read the request ID from, such as X-Request-Id. If not given,
+	// or if the header is not present on the request, a request ID will be generated
+	RequestIDHeader string
+	// RequestIDValidator optionally decides whether an inbound request ID is acceptable, a request ID is generated in
+	// place of any that are not. If not given will default to ValidRequestID
+	RequestIDValidator func(requestID string) bool
+	// ResponseRequestIDHeader is the optional response header the request ID will be echoed on. If not given, will
+	// default to RequestIDHeader. If neither are given, the request ID will not be echoed. Unary errors are replaced
+	// with a copy carrying the request ID in its metadata, so errors shared between requests are never modified
+	ResponseRequestIDHeader string
+	// RequestIDGenerator is the optional generator used when no inbound request ID is present. If not given, will
+	// default to a monotonic ULID generator
+	RequestIDGenerator RequestIDGenerator
+	// AttachProcedure attaches the called procedure to the logger under the "procedure" key
+	AttachProcedure bool
+	// AttachPeerAddr attaches the callers address to the logger under the "peer-addr" key
+	AttachPeerAddr bool
+	// AttachProtocol attaches the RPC protocol in use (connect, grpc, grpcweb) to the logger under the "protocol" key
+	AttachProtocol bool
+	// AttachHTTPMethod attaches the HTTP method of the request to the logger under the "http-method" key
+	AttachHTTPMethod bool
+	// AttachHeaders is an optional list of request headers to attach to the logger, each under a "header-<name>" key
+	// with the name lowercased. Headers not present on the request are omitted, and the values of
+	// DefaultRedactedHeaders are replaced with payload.DefaultRedactionPlaceholder
+	AttachHeaders []string
 }
 
 func NewContextLoggerInterceptor(config ContextLoggerInterceptorConfig) *ContextLoggerInterceptor {
+	responseHeader := config.ResponseRequestIDHeader
+	if responseHeader == "" {
+		responseHeader = config.RequestIDHeader
+	}
+
+	generator := config.RequestIDGenerator
+	if generator == nil {
+		generator = NewULIDGenerator()
+	}
+
+	validator := config.RequestIDValidator
+	if validator == nil {
+		validator = ValidRequestID
+	}
+
 	return &ContextLoggerInterceptor{
-		rootLogger:      config.RootLogger,
-		attachRequestID: !config.NoAttachRequestID,
+		rootLogger:       config.RootLogger,
+		attachRequestID:  !config.NoAttachRequestID,
+		requestIDHeader:  config.RequestIDHeader,
+		validRequestID:   validator,
+		responseIDHeader: responseHeader,
+		generator:        generator,
+		attachProcedure:  config.AttachProcedure,
+		attachPeerAddr:   config.AttachPeerAddr,
+		attachProtocol:   config.AttachProtocol,
+		attachHTTPMethod: config.AttachHTTPMethod,
+		attachHeaders:    config.AttachHeaders,
+		headerRedactor:   newHeaderLogger(&HeaderLogging{}),
 	}
 }
 
@@ -27,28 +81,171 @@ var _ connect.Interceptor = (*ContextLoggerInterceptor)(nil)
 
 type ContextLoggerInterceptor struct {
 	unimplemented.UnimplementedInterceptor
-	rootLogger      logr.Logger
-	attachRequestID bool
+	rootLogger       logr.Logger
+	attachRequestID  bool
+	requestIDHeader  string
+	validRequestID   func(string) bool
+	responseIDHeader string
+	generator        RequestIDGenerator
+	attachProcedure  bool
+	attachPeerAddr   bool
+	attachProtocol   bool
+	attachHTTPMethod bool
+	attachHeaders    []string
+	headerRedactor   *headerLogger
+}
+
+// requestMetadata is the subset of request information shared by unary requests & streaming connections
+type requestMetadata struct {
+	spec       connect.Spec
+	peer       connect.Peer
+	httpMethod string
+	header     http.Header
 }
 
 func (c *ContextLoggerInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-		return next(c.embed(ctx), req)
+		ctx, requestID := c.embed(ctx, requestMetadata{
+			spec:       req.Spec(),
+			peer:       req.Peer(),
+			httpMethod: req.HTTPMethod(),
+			header:     req.Header(),
+		})
+
+		resp, err := next(ctx, req)
+
+		if requestID != "" && c.responseIDHeader != "" {
+			if resp != nil {
+				resp.Header().Set(c.responseIDHeader, requestID)
+			}
+			if err != nil {
+				err = withMeta(err, c.responseIDHeader, requestID)
+			}
+		}
+
+		return resp, err
 	})
 }
 
 func (c *ContextLoggerInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
 	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
-		return next(c.embed(ctx), conn)
+		ctx, requestID := c.embed(ctx, requestMetadata{
+			spec: conn.Spec(),
+			peer: conn.Peer(),
+			// Streaming calls are always made over POST
+			httpMethod: http.MethodPost,
+			header:     conn.RequestHeader(),
+		})
+
+		if requestID != "" && c.responseIDHeader != "" {
+			conn.ResponseHeader().Set(c.responseIDHeader, requestID)
+		}
+
+		return next(ctx, conn)
 	})
 }
 
-func (c *ContextLoggerInterceptor) embed(ctx context.Context) context.Context {
-	reqLogger := c.rootLogger
+// asConnectError returns err as is if it is or wraps a connect.Error, otherwise wraps it in a connect.Error so that
+// metadata can be attached. Context errors keep the code connect would otherwise have given them
+func asConnectError(err error) error {
+	if connectErr := new(connect.Error); errors.As(err, &connectErr) {
+		return err
+	}
+
+	code := connect.CodeUnknown
+	switch {
+	case errors.Is(err, context.Canceled):
+		code = connect.CodeCanceled
+	case errors.Is(err, context.DeadlineExceeded):
+		code = connect.CodeDeadlineExceeded
+	}
+	return connect.NewError(code, err)
+}
+
+// withMeta returns a copy of the connect.Error in err, wrapping err in one if required, with the given metadata set.
+// The error is never modified in place, as handlers commonly return package level sentinel errors shared by every
+// request
+func withMeta(err error, key string, value string) error {
+	connectErr := new(connect.Error)
+	if !errors.As(asConnectError(err), &connectErr) {
+		return err
+	}
+
+	// Meta lazily allocates on its receiver, so is only ever called on a copy of the shared error
+	original := *connectErr
+
+	copied := connect.NewError(original.Code(), original.Unwrap())
+	if connect.IsWireError(connectErr) {
+		copied = connect.NewWireError(original.Code(), original.Unwrap())
+	}
+	for _, detail := range original.Details() {
+		copied.AddDetail(detail)
+	}
+	for name, values := range original.Meta() {
+		copied.Meta()[name] = slices.Clone(values)
+	}
+	copied.Meta().Set(key, value)
+
+	return copied
+}
+
+// embed attaches the request level logger, and request ID if configured, to the context. The returned request ID will
+// be empty if request IDs are disabled
+func (c *ContextLoggerInterceptor) embed(ctx context.Context, meta requestMetadata) (context.Context, string) {
+	keysAndValues := []any{}
+	requestID := ""
 
 	if c.attachRequestID {
-		reqLogger = c.rootLogger.WithValues("request-id", ulid.Make().String())
+		if c.requestIDHeader != "" {
+			requestID = meta.header.Get(c.requestIDHeader)
+		}
+		// Inbound IDs are attached to every log line & echoed back, so anything unexpected is replaced
+		if requestID == "" || !c.validRequestID(requestID) {
+			requestID = c.generator.NewRequestID()
+		}
+
+		keysAndValues = append(keysAndValues, "request-id", requestID)
+		ctx = ContextWithRequestID(ctx, requestID)
+	}
+
+	if c.attachProcedure {
+		keysAndValues = append(keysAndValues, "procedure", meta.spec.Procedure)
+	}
+	if c.attachPeerAddr {
+		keysAndValues = append(keysAndValues, "peer-addr", meta.peer.Addr)
+	}
+	if c.attachProtocol {
+		keysAndValues = append(keysAndValues, "protocol", meta.peer.Protocol)
 	}
+	if c.attachHTTPMethod {
+		keysAndValues = append(keysAndValues, "http-method", meta.httpMethod)
+	}
+	for _, name := range c.attachHeaders {
+		if value := meta.header.Get(name); value != "" {
+			if c.headerRedactor.redacted(http.CanonicalHeaderKey(name)) {
+				value = c.headerRedactor.placeholder
+			}
+			keysAndValues = append(keysAndValues, "header-"+strings.ToLower(name), value)
+		}
+	}
+
+	reqLogger := c.rootLogger
+	if len(keysAndValues) > 0 {
+		reqLogger = c.rootLogger.WithValues(keysAndValues...)
+	}
+
+	return logr.NewContext(ctx, reqLogger), requestID
+}
+
+type requestIDContextKey struct{}
+
+// ContextWithRequestID returns a copy of the context carrying the given request ID
+func ContextWithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey{}, requestID)
+}
 
-	return logr.NewContext(ctx, reqLogger)
+// RequestIDFromContext returns the request ID attached to the context by the ContextLoggerInterceptor, if any
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	requestID, ok := ctx.Value(requestIDContextKey{}).(string)
+	return requestID, ok
 }
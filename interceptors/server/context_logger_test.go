@@ -0,0 +1,341 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/nicjohnson145/connecthelp/interceptors/payload"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestContextLoggerRequestID(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name              string
+		config            ContextLoggerInterceptorConfig
+		inbound           string
+		err               error
+		expectedID        string
+		expectedEcho      string
+		expectedErrorCode connect.Code
+	}{
+		{
+			name:       "generated without header",
+			config:     ContextLoggerInterceptorConfig{},
+			expectedID: "gen-1",
+		},
+		{
+			name: "read from inbound header",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+			},
+			inbound:      "inbound",
+			expectedID:   "inbound",
+			expectedEcho: "inbound",
+		},
+		{
+			name: "generated when inbound too long",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+			},
+			inbound:      strings.Repeat("a", MaxRequestIDLength+1),
+			expectedID:   "gen-1",
+			expectedEcho: "gen-1",
+		},
+		{
+			name: "generated when inbound has invalid characters",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+			},
+			inbound:      "evil\", \"admin\": true",
+			expectedID:   "gen-1",
+			expectedEcho: "gen-1",
+		},
+		{
+			name: "custom validator",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+				RequestIDValidator: func(requestID string) bool {
+					return strings.HasPrefix(requestID, "trusted ")
+				},
+			},
+			inbound:      "trusted id",
+			expectedID:   "trusted id",
+			expectedEcho: "trusted id",
+		},
+		{
+			name: "generated when header missing",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+			},
+			expectedID:   "gen-1",
+			expectedEcho: "gen-1",
+		},
+		{
+			name: "echoed on separate response header",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader:         "X-Request-Id",
+				ResponseRequestIDHeader: "X-Trace",
+			},
+			inbound:      "inbound",
+			expectedID:   "inbound",
+			expectedEcho: "inbound",
+		},
+		{
+			name: "echoed on connect error",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+			},
+			inbound:           "inbound",
+			err:               connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expectedID:        "inbound",
+			expectedEcho:      "inbound",
+			expectedErrorCode: connect.CodeNotFound,
+		},
+		{
+			name: "echoed on plain error",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+			},
+			inbound:           "inbound",
+			err:               errors.New("broken"),
+			expectedID:        "inbound",
+			expectedEcho:      "inbound",
+			expectedErrorCode: connect.CodeUnknown,
+		},
+		{
+			name: "context error keeps code",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader: "X-Request-Id",
+			},
+			inbound:           "inbound",
+			err:               context.DeadlineExceeded,
+			expectedID:        "inbound",
+			expectedEcho:      "inbound",
+			expectedErrorCode: connect.CodeDeadlineExceeded,
+		},
+		{
+			name: "disabled",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDHeader:   "X-Request-Id",
+				NoAttachRequestID: true,
+			},
+			inbound: "inbound",
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			newInterceptor := func() *ContextLoggerInterceptor {
+				config := tc.config
+				config.RequestIDGenerator = NewSequenceGenerator("gen")
+				return NewContextLoggerInterceptor(config)
+			}
+
+			echoHeader := tc.config.ResponseRequestIDHeader
+			if echoHeader == "" {
+				echoHeader = "X-Request-Id"
+			}
+
+			t.Run("unary", func(t *testing.T) {
+				requestID := ""
+				unary := newInterceptor().WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+					requestID, _ = RequestIDFromContext(ctx)
+					if tc.err != nil {
+						return nil, tc.err
+					}
+					return connect.NewResponse(&emptypb.Empty{}), nil
+				})
+
+				req := connect.NewRequest(&emptypb.Empty{})
+				if tc.inbound != "" {
+					req.Header().Set("X-Request-Id", tc.inbound)
+				}
+
+				resp, err := unary(context.Background(), req)
+				require.Equal(t, tc.expectedID, requestID)
+				if tc.err == nil {
+					require.NoError(t, err)
+					require.Equal(t, tc.expectedEcho, resp.Header().Get(echoHeader))
+				} else {
+					require.ErrorContains(t, err, tc.err.Error())
+					connectErr := new(connect.Error)
+					require.ErrorAs(t, err, &connectErr)
+					require.Equal(t, tc.expectedErrorCode, connectErr.Code())
+					require.Equal(t, tc.expectedEcho, connectErr.Meta().Get(echoHeader))
+				}
+			})
+
+			t.Run("streaming", func(t *testing.T) {
+				requestID := ""
+				stream := newInterceptor().WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+					requestID, _ = RequestIDFromContext(ctx)
+					return nil
+				})
+
+				conn := newTestStreamConn("/pkg.v1.Service/Stream")
+				if tc.inbound != "" {
+					conn.requestHeader.Set("X-Request-Id", tc.inbound)
+				}
+
+				require.NoError(t, stream(context.Background(), conn))
+				require.Equal(t, tc.expectedID, requestID)
+				require.Equal(t, tc.expectedEcho, conn.responseHeader.Get(echoHeader))
+			})
+		})
+	}
+}
+
+var errSharedNotFound = connect.NewError(connect.CodeNotFound, errors.New("shared missing"))
+
+func TestContextLoggerSharedError(t *testing.T) {
+	t.Parallel()
+
+	detail, err := connect.NewErrorDetail(&emptypb.Empty{})
+	require.NoError(t, err)
+	shared := connect.NewError(connect.CodeNotFound, errors.New("shared missing"))
+	shared.AddDetail(detail)
+	shared.Meta().Set("X-Existing", "kept")
+
+	testData := []struct {
+		name   string
+		shared *connect.Error
+	}{
+		{name: "without metadata", shared: errSharedNotFound},
+		{name: "with metadata & details", shared: shared},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inter := NewContextLoggerInterceptor(ContextLoggerInterceptorConfig{
+				RequestIDHeader:    "X-Request-Id",
+				RequestIDGenerator: NewSequenceGenerator("gen"),
+			})
+			unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+				return nil, tc.shared
+			})
+
+			// Run concurrently so the race detector catches any write to the shared error
+			errs := make([]error, 8)
+			wg := sync.WaitGroup{}
+			for i := range errs {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					_, errs[i] = unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+				}()
+			}
+			wg.Wait()
+
+			seen := map[string]bool{}
+			for _, err := range errs {
+				connectErr := new(connect.Error)
+				require.ErrorAs(t, err, &connectErr)
+				require.NotSame(t, tc.shared, connectErr)
+				require.Equal(t, connect.CodeNotFound, connectErr.Code())
+				require.Equal(t, "shared missing", connectErr.Message())
+				require.Len(t, connectErr.Details(), len(tc.shared.Details()))
+
+				requestID := connectErr.Meta().Get("X-Request-Id")
+				require.NotEmpty(t, requestID)
+				require.False(t, seen[requestID])
+				seen[requestID] = true
+
+				for name := range tc.shared.Meta() {
+					require.Equal(t, tc.shared.Meta().Values(name), connectErr.Meta().Values(name))
+				}
+			}
+
+			require.Empty(t, tc.shared.Meta().Get("X-Request-Id"))
+		})
+	}
+}
+
+func TestContextLoggerAttach(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name     string
+		config   ContextLoggerInterceptorConfig
+		expected map[string]any
+	}{
+		{
+			name:     "nothing attached",
+			config:   ContextLoggerInterceptorConfig{NoAttachRequestID: true},
+			expected: map[string]any{},
+		},
+		{
+			name: "request metadata",
+			config: ContextLoggerInterceptorConfig{
+				NoAttachRequestID: true,
+				AttachProcedure:   true,
+				AttachPeerAddr:    true,
+				AttachProtocol:    true,
+				AttachHTTPMethod:  true,
+			},
+			expected: map[string]any{
+				"procedure":   "/pkg.v1.Service/Stream",
+				"peer-addr":   "127.0.0.1:1234",
+				"protocol":    "connect",
+				"http-method": "POST",
+			},
+		},
+		{
+			name: "headers",
+			config: ContextLoggerInterceptorConfig{
+				NoAttachRequestID: true,
+				AttachHeaders:     []string{"X-Tenant", "authorization", "X-Missing"},
+			},
+			expected: map[string]any{
+				"header-x-tenant":      "acme",
+				"header-authorization": payload.DefaultRedactionPlaceholder,
+			},
+		},
+		{
+			name: "request id",
+			config: ContextLoggerInterceptorConfig{
+				RequestIDGenerator: NewSequenceGenerator("gen"),
+			},
+			expected: map[string]any{
+				"request-id": "gen-1",
+			},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var line map[string]any
+			tc.config.RootLogger = funcr.NewJSON(func(obj string) {
+				line = map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+			}, funcr.Options{})
+
+			stream := NewContextLoggerInterceptor(tc.config).WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+				logr.FromContextOrDiscard(ctx).Info("handled")
+				return nil
+			})
+
+			conn := newTestStreamConn("/pkg.v1.Service/Stream")
+			conn.requestHeader.Set("X-Tenant", "acme")
+			conn.requestHeader.Set("Authorization", "Bearer secret")
+			require.NoError(t, stream(context.Background(), conn))
+
+			for _, key := range []string{"logger", "level", "msg"} {
+				delete(line, key)
+			}
+			require.Equal(t, tc.expected, line)
+		})
+	}
+}
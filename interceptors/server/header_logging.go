@@ -0,0 +1,140 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"github.com/nicjohnson145/connecthelp/interceptors/payload"
+)
+
+// DefaultRedactedHeaders are the headers whose values are always redacted when logged
+var DefaultRedactedHeaders = []string{"Authorization", "Proxy-Authorization", "Cookie", "Set-Cookie"}
+
+// HeaderLogging configures logging of request headers, response headers & trailers
+type HeaderLogging struct {
+	// RequestHeaders logs the request headers under the "request-headers" key
+	RequestHeaders bool
+	// ResponseHeaders logs the response headers under the "response-headers" key
+	ResponseHeaders bool
+	// Trailers logs the response trailers under the "response-trailers" key
+	Trailers bool
+	// AllowHeaders optionally restricts logging to only the listed headers
+	AllowHeaders []string
+	// DenyHeaders optionally lists headers that are never logged
+	DenyHeaders []string
+	// RedactHeaders optionally lists headers whose values are redacted, in addition to DefaultRedactedHeaders
+	RedactHeaders []string
+	// RedactionPlaceholder is the optional value redacted headers are replaced with. If not given will default to
+	// payload.DefaultRedactionPlaceholder
+	RedactionPlaceholder string
+}
+
+// headerLogger filters & redacts headers for logging. A nil headerLogger logs nothing
+type headerLogger struct {
+	config      HeaderLogging
+	allow       map[string]struct{}
+	deny        map[string]struct{}
+	redact      map[string]struct{}
+	placeholder string
+}
+
+func newHeaderLogger(config *HeaderLogging) *headerLogger {
+	if config == nil {
+		return nil
+	}
+
+	toSet := func(names ...[]string) map[string]struct{} {
+		set := map[string]struct{}{}
+		for _, list := range names {
+			for _, name := range list {
+				set[http.CanonicalHeaderKey(name)] = struct{}{}
+			}
+		}
+		return set
+	}
+
+	placeholder := config.RedactionPlaceholder
+	if placeholder == "" {
+		placeholder = payload.DefaultRedactionPlaceholder
+	}
+
+	return &headerLogger{
+		config:      *config,
+		allow:       toSet(config.AllowHeaders),
+		deny:        toSet(config.DenyHeaders),
+		redact:      toSet(DefaultRedactedHeaders, config.RedactHeaders),
+		placeholder: placeholder,
+	}
+}
+
+// requestValues returns the key/value pairs for the request headers, if configured
+func (h *headerLogger) requestValues(header http.Header) []any {
+	if h == nil || !h.config.RequestHeaders {
+		return nil
+	}
+	return []any{"request-headers", h.filter(header)}
+}
+
+// responseValues returns the key/value pairs for the response headers & trailers, if configured. A nil trailer
+// indicates trailers are not available, and they are omitted
+func (h *headerLogger) responseValues(header http.Header, trailer http.Header) []any {
+	if h == nil {
+		return nil
+	}
+
+	keysAndValues := []any{}
+	if h.config.ResponseHeaders {
+		keysAndValues = append(keysAndValues, "response-headers", h.filter(header))
+	}
+	if h.config.Trailers && trailer != nil {
+		keysAndValues = append(keysAndValues, "response-trailers", h.filter(trailer))
+	}
+	return keysAndValues
+}
+
+// unaryResponseValues returns the key/value pairs for a unary response. Failed calls have no response, so the
+// metadata attached to the error is logged as the response headers instead
+func (h *headerLogger) unaryResponseValues(resp connect.AnyResponse, err error) []any {
+	if resp != nil {
+		return h.responseValues(resp.Header(), resp.Trailer())
+	}
+
+	if connectErr := new(connect.Error); errors.As(err, &connectErr) {
+		return h.responseValues(connectErr.Meta(), nil)
+	}
+	return h.responseValues(nil, nil)
+}
+
+func (h *headerLogger) filter(header http.Header) map[string][]string {
+	filtered := map[string][]string{}
+
+	for name, values := range header {
+		name = http.CanonicalHeaderKey(name)
+
+		if _, ok := h.deny[name]; ok {
+			continue
+		}
+		if _, ok := h.allow[name]; len(h.allow) > 0 && !ok {
+			continue
+		}
+
+		if h.redacted(name) {
+			redacted := make([]string, len(values))
+			for i := range values {
+				redacted[i] = h.placeholder
+			}
+			values = redacted
+		}
+
+		filtered[name] = values
+	}
+
+	return filtered
+}
+
+// redacted reports whether the values of the given canonical header name should be redacted
+func (h *headerLogger) redacted(name string) bool {
+	_, ok := h.redact[name]
+	return ok
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeaderLoggerFilter(t *testing.T) {
+	t.Parallel()
+
+	header := http.Header{
+		"Authorization": {"Bearer secret"},
+		"Cookie":        {"a=b", "c=d"},
+		"X-Api-Key":     {"key"},
+		"X-Request-Id":  {"123"},
+		"User-Agent":    {"test"},
+	}
+
+	testData := []struct {
+		name     string
+		config   HeaderLogging
+		expected map[string][]string
+	}{
+		{
+			name:   "default redactions",
+			config: HeaderLogging{},
+			expected: map[string][]string{
+				"Authorization": {"[REDACTED]"},
+				"Cookie":        {"[REDACTED]", "[REDACTED]"},
+				"X-Api-Key":     {"key"},
+				"X-Request-Id":  {"123"},
+				"User-Agent":    {"test"},
+			},
+		},
+		{
+			name: "custom redactions",
+			config: HeaderLogging{
+				RedactHeaders:        []string{"x-api-key"},
+				RedactionPlaceholder: "***",
+			},
+			expected: map[string][]string{
+				"Authorization": {"***"},
+				"Cookie":        {"***", "***"},
+				"X-Api-Key":     {"***"},
+				"X-Request-Id":  {"123"},
+				"User-Agent":    {"test"},
+			},
+		},
+		{
+			name: "allowlist",
+			config: HeaderLogging{
+				AllowHeaders: []string{"x-request-id", "Authorization"},
+			},
+			expected: map[string][]string{
+				"Authorization": {"[REDACTED]"},
+				"X-Request-Id":  {"123"},
+			},
+		},
+		{
+			name: "denylist",
+			config: HeaderLogging{
+				DenyHeaders: []string{"cookie", "user-agent"},
+			},
+			expected: map[string][]string{
+				"Authorization": {"[REDACTED]"},
+				"X-Api-Key":     {"key"},
+				"X-Request-Id":  {"123"},
+			},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			require.Equal(t, tc.expected, newHeaderLogger(&tc.config).filter(header))
+		})
+	}
+}
+
+func TestHeaderLoggerDisabled(t *testing.T) {
+	t.Parallel()
+
+	var h *headerLogger
+	require.Nil(t, h.requestValues(http.Header{"A": {"b"}}))
+	require.Nil(t, h.responseValues(http.Header{"A": {"b"}}, nil))
+
+	h = newHeaderLogger(&HeaderLogging{ResponseHeaders: true})
+	require.Nil(t, h.requestValues(http.Header{"A": {"b"}}))
+	require.Equal(t, []any{"response-headers", map[string][]string{"A": {"b"}}}, h.responseValues(http.Header{"A": {"b"}}, nil))
+}
@@ -2,12 +2,47 @@ package server
 
 import (
 	"context"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/go-logr/logr"
 	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
 )
 
+// LogLevel is the level a message is logged at. Non-negative values log at the matching logr verbosity, LogLevelError
+// logs with logr's Error
+type LogLevel int
+
+const (
+	LogLevelError LogLevel = -1
+	LogLevelInfo  LogLevel = 0
+	LogLevelDebug LogLevel = 1
+)
+
+// DefaultCodeLogLevels returns the default mapping of connect codes to the level error completions are logged at.
+// Client-caused codes log at LogLevelInfo, codes indicating a server side failure log at LogLevelError. A new map is
+// returned on each call, so callers are free to modify it
+func DefaultCodeLogLevels() map[connect.Code]LogLevel {
+	return map[connect.Code]LogLevel{
+		connect.CodeCanceled:           LogLevelInfo,
+		connect.CodeUnknown:            LogLevelError,
+		connect.CodeInvalidArgument:    LogLevelInfo,
+		connect.CodeDeadlineExceeded:   LogLevelError,
+		connect.CodeNotFound:           LogLevelInfo,
+		connect.CodeAlreadyExists:      LogLevelInfo,
+		connect.CodePermissionDenied:   LogLevelInfo,
+		connect.CodeResourceExhausted:  LogLevelInfo,
+		connect.CodeFailedPrecondition: LogLevelInfo,
+		connect.CodeAborted:            LogLevelInfo,
+		connect.CodeOutOfRange:         LogLevelInfo,
+		connect.CodeUnimplemented:      LogLevelInfo,
+		connect.CodeInternal:           LogLevelError,
+		connect.CodeUnavailable:        LogLevelError,
+		connect.CodeDataLoss:           LogLevelError,
+		connect.CodeUnauthenticated:    LogLevelInfo,
+	}
+}
+
 type MethodLoggingInterceptorConfig struct {
 	// Logger is the optional logger the method calls will be logged with, if not given, will attempt to use the context
 	// logger. If neither are present, no logging will be done
@@ -16,13 +51,30 @@ type MethodLoggingInterceptorConfig struct {
 	LogSuccessfulCompletion bool
 	// LogErrorCompletion optionally will log a message on request error
 	LogErrorCompletion bool
+	// AccessLog optionally replaces the received & completion messages with a single message logged once the request
+	// has completed, regardless of outcome. LogSuccessfulCompletion and LogErrorCompletion are ignored when set
+	AccessLog bool
+	// CodeLogLevels is the optional mapping of connect codes to the level error completions are logged at. If not
+	// given, will default to DefaultCodeLogLevels. Codes missing from the mapping log at LogLevelError
+	CodeLogLevels map[connect.Code]LogLevel
+	// Headers optionally logs request headers with the received message, and response headers & trailers with the
+	// completion message. In AccessLog mode everything is logged with the single completion message
+	Headers *HeaderLogging
 }
 
 func NewMethodLoggingInterceptor(config MethodLoggingInterceptorConfig) *MethodLoggingInterceptor {
+	codeLogLevels := config.CodeLogLevels
+	if codeLogLevels == nil {
+		codeLogLevels = DefaultCodeLogLevels()
+	}
+
 	interceptor := &MethodLoggingInterceptor{
 		logger:                  config.Logger,
 		logSuccessfulCompletion: config.LogSuccessfulCompletion,
 		logErrorCompletion:      config.LogErrorCompletion,
+		accessLog:               config.AccessLog,
+		codeLogLevels:           codeLogLevels,
+		headers:                 newHeaderLogger(config.Headers),
 	}
 
 	return interceptor
@@ -35,22 +87,30 @@ type MethodLoggingInterceptor struct {
 	logger                  *logr.Logger
 	logSuccessfulCompletion bool
 	logErrorCompletion      bool
+	accessLog               bool
+	codeLogLevels           map[connect.Code]LogLevel
+	headers                 *headerLogger
 }
 
 func (m *MethodLoggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
 		log := m.getLogger(ctx).WithValues("path", req.Spec().Procedure)
 
-		log.Info("request recieved")
+		requestHeaders := m.headers.requestValues(req.Header())
+		if !m.accessLog {
+			log.Info("request recieved", requestHeaders...)
+		}
 
+		start := time.Now()
 		resp, err := next(ctx, req)
 
-		if err != nil && m.logErrorCompletion {
-			log.Error(err, "request completed with error")
-		}
-		if err == nil && m.logSuccessfulCompletion {
-			log.Info("request completed")
+		keysAndValues := completionValues(start, req.Peer(), err)
+		if m.accessLog {
+			keysAndValues = append(keysAndValues, requestHeaders...)
 		}
+		keysAndValues = append(keysAndValues, m.headers.unaryResponseValues(resp, err)...)
+
+		m.logCompletion(log, "request", err, keysAndValues...)
 
 		return resp, err
 	})
@@ -60,21 +120,69 @@ func (m *MethodLoggingInterceptor) WrapStreamingHandler(next connect.StreamingHa
 	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
 		log := m.getLogger(ctx).WithValues("path", conn.Spec().Procedure)
 
-		log.Info("stream started")
+		requestHeaders := m.headers.requestValues(conn.RequestHeader())
+		if !m.accessLog {
+			log.Info("stream started", requestHeaders...)
+		}
 
-		err := next(ctx, conn)
+		countingConn := &countingHandlerConn{StreamingHandlerConn: conn}
 
-		if err != nil && m.logErrorCompletion {
-			log.Error(err, "stream ended with error")
-		}
-		if err == nil && m.logSuccessfulCompletion {
-			log.Info("stream completed")
+		start := time.Now()
+		err := next(ctx, countingConn)
+
+		keysAndValues := append(
+			completionValues(start, conn.Peer(), err),
+			"messages-received", countingConn.received,
+			"messages-sent", countingConn.sent,
+		)
+		if m.accessLog {
+			keysAndValues = append(keysAndValues, requestHeaders...)
 		}
+		keysAndValues = append(keysAndValues, m.headers.responseValues(conn.ResponseHeader(), conn.ResponseTrailer())...)
+
+		m.logCompletion(log, "stream", err, keysAndValues...)
 
 		return err
 	})
 }
 
+// logCompletion logs the completion of a request or stream, according to the configured completion logging
+func (m *MethodLoggingInterceptor) logCompletion(log logr.Logger, kind string, err error, keysAndValues ...any) {
+	switch {
+	case err != nil && (m.accessLog || m.logErrorCompletion):
+		m.logError(log, err, kind+" completed with error", keysAndValues...)
+	case err == nil && (m.accessLog || m.logSuccessfulCompletion):
+		log.Info(kind+" completed", keysAndValues...)
+	}
+}
+
+// logError logs the error at the level configured for its connect code
+func (m *MethodLoggingInterceptor) logError(log logr.Logger, err error, msg string, keysAndValues ...any) {
+	level, ok := m.codeLogLevels[connect.CodeOf(err)]
+	if !ok || level <= LogLevelError {
+		log.Error(err, msg, keysAndValues...)
+		return
+	}
+	log.V(int(level)).Info(msg, append([]any{"error", err.Error()}, keysAndValues...)...)
+}
+
+// completionValues returns the key/value pairs common to all completion log messages
+func completionValues(start time.Time, peer connect.Peer, err error) []any {
+	return []any{
+		"duration", time.Since(start),
+		"code", codeString(err),
+		"protocol", peer.Protocol,
+	}
+}
+
+// codeString returns the string form of the errors connect code, or "ok" for a nil error
+func codeString(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return connect.CodeOf(err).String()
+}
+
 func (m *MethodLoggingInterceptor) getLogger(ctx context.Context) logr.Logger {
 	if m.logger != nil {
 		return *m.logger
@@ -82,3 +190,26 @@ func (m *MethodLoggingInterceptor) getLogger(ctx context.Context) logr.Logger {
 		return logr.FromContextOrDiscard(ctx)
 	}
 }
+
+// countingHandlerConn wraps a streaming connection, counting the messages successfully received & sent
+type countingHandlerConn struct {
+	connect.StreamingHandlerConn
+	received int
+	sent     int
+}
+
+func (c *countingHandlerConn) Receive(msg any) error {
+	err := c.StreamingHandlerConn.Receive(msg)
+	if err == nil {
+		c.received++
+	}
+	return err
+}
+
+func (c *countingHandlerConn) Send(msg any) error {
+	err := c.StreamingHandlerConn.Send(msg)
+	if err == nil {
+		c.sent++
+	}
+	return err
+}
@@ -0,0 +1,183 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+func TestMethodLoggingCodeLogLevels(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name          string
+		config        MethodLoggingInterceptorConfig
+		err           error
+		expectedLevel *float64
+	}{
+		{
+			name:          "default client code logs at info",
+			err:           connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expectedLevel: ptr(float64(LogLevelInfo)),
+		},
+		{
+			name:          "default server code logs at error",
+			err:           connect.NewError(connect.CodeInternal, errors.New("broken")),
+			expectedLevel: nil,
+		},
+		{
+			name:          "non-connect error logs at error",
+			err:           errors.New("broken"),
+			expectedLevel: nil,
+		},
+		{
+			name: "custom mapping",
+			config: MethodLoggingInterceptorConfig{
+				CodeLogLevels: map[connect.Code]LogLevel{
+					connect.CodeNotFound: LogLevelDebug,
+				},
+			},
+			err:           connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expectedLevel: ptr(float64(LogLevelDebug)),
+		},
+		{
+			name: "code missing from custom mapping logs at error",
+			config: MethodLoggingInterceptorConfig{
+				CodeLogLevels: map[connect.Code]LogLevel{},
+			},
+			err:           connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expectedLevel: nil,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines := []map[string]any{}
+			log := funcr.NewJSON(func(obj string) {
+				line := map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				lines = append(lines, line)
+			}, funcr.Options{Verbosity: 1})
+
+			inter := NewMethodLoggingInterceptor(tc.config)
+			inter.logError(log, tc.err, "request completed with error")
+
+			require.Len(t, lines, 1)
+			level, ok := lines[0]["level"].(float64)
+			if tc.expectedLevel == nil {
+				require.False(t, ok, "expected error level log")
+			} else {
+				require.True(t, ok, "expected info level log")
+				require.Equal(t, *tc.expectedLevel, level)
+			}
+			require.Equal(t, tc.err.Error(), lines[0]["error"])
+		})
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestMethodLoggingCompletion(t *testing.T) {
+	t.Parallel()
+
+	failingHandler := func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		if err := echoStreamHandler(ctx, conn); err != nil {
+			return err
+		}
+		return connect.NewError(connect.CodeInternal, errors.New("broken"))
+	}
+
+	testData := []struct {
+		name             string
+		config           MethodLoggingInterceptorConfig
+		handler          connect.StreamingHandlerFunc
+		expectedMessages []string
+		expectedCode     string
+	}{
+		{
+			name:             "completion not logged by default",
+			config:           MethodLoggingInterceptorConfig{},
+			handler:          echoStreamHandler,
+			expectedMessages: []string{"stream started"},
+		},
+		{
+			name: "successful completion",
+			config: MethodLoggingInterceptorConfig{
+				LogSuccessfulCompletion: true,
+			},
+			handler:          echoStreamHandler,
+			expectedMessages: []string{"stream started", "stream completed"},
+			expectedCode:     "ok",
+		},
+		{
+			name: "error completion",
+			config: MethodLoggingInterceptorConfig{
+				LogErrorCompletion: true,
+			},
+			handler:          failingHandler,
+			expectedMessages: []string{"stream started", "stream completed with error"},
+			expectedCode:     "internal",
+		},
+		{
+			name: "access log success",
+			config: MethodLoggingInterceptorConfig{
+				AccessLog: true,
+			},
+			handler:          echoStreamHandler,
+			expectedMessages: []string{"stream completed"},
+			expectedCode:     "ok",
+		},
+		{
+			name: "access log error",
+			config: MethodLoggingInterceptorConfig{
+				AccessLog: true,
+			},
+			handler:          failingHandler,
+			expectedMessages: []string{"stream completed with error"},
+			expectedCode:     "internal",
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines := []map[string]any{}
+			log := funcr.NewJSON(func(obj string) {
+				line := map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				lines = append(lines, line)
+			}, funcr.Options{})
+			tc.config.Logger = &log
+
+			stream := NewMethodLoggingInterceptor(tc.config).WrapStreamingHandler(tc.handler)
+			conn := newTestStreamConn("/pkg.v1.Service/Echo", wrapperspb.String("a"), wrapperspb.String("b"))
+			_ = stream(context.Background(), conn)
+
+			messages := []string{}
+			for _, line := range lines {
+				messages = append(messages, line["msg"].(string))
+				require.Equal(t, "/pkg.v1.Service/Echo", line["path"])
+			}
+			require.Equal(t, tc.expectedMessages, messages)
+
+			if tc.expectedCode == "" {
+				return
+			}
+			completion := lines[len(lines)-1]
+			require.Equal(t, tc.expectedCode, completion["code"])
+			require.Equal(t, "connect", completion["protocol"])
+			require.Contains(t, completion, "duration")
+			require.Equal(t, float64(2), completion["messages-received"])
+			require.Equal(t, float64(2), completion["messages-sent"])
+		})
+	}
+}
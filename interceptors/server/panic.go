@@ -2,29 +2,88 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
 	"runtime"
+	"strings"
+	"time"
 
 	"connectrpc.com/connect"
 	"github.com/go-logr/logr"
 	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+)
+
+// PanicErrorMode controls what a client is told about a recovered panic
+type PanicErrorMode string
+
+const (
+	// PanicErrorModeMessage returns the panic value to the client as the error message
+	PanicErrorModeMessage PanicErrorMode = ""
+	// PanicErrorModeOpaque returns a generic message containing only a correlation ID, which is also logged and
+	// reported alongside the panic. The request ID is used when present, otherwise one is generated
+	PanicErrorModeOpaque PanicErrorMode = "opaque"
+	// PanicErrorModeDevelopment returns the panic value as the error message, and attaches the stack as an
+	// errdetails.DebugInfo error detail. It should not be used in production
+	PanicErrorModeDevelopment PanicErrorMode = "development"
 )
 
 const (
-	DefaultPanicStackBufferSize = 8192
+	DefaultPanicStackBufferSize    = 8192
+	DefaultPanicMaxStackBufferSize = 64 << 20
 )
 
 type PanicInterceptorConfig struct {
 	// Logger is the optional logger the panics will be logged with, if not given, will attempt to use the context
 	// logger. If neither are present, no logging will be done
 	Logger *logr.Logger
-	// StackBufferSize is the optional stack size configuration. If not given will default to DefaultPanicStackBufferSize
+	// StackBufferSize is the optional initial stack size configuration. If not given will default to
+	// DefaultPanicStackBufferSize. The buffer is grown until the whole stack fits, up to MaxStackBufferSize
 	StackBufferSize *int
+	// MaxStackBufferSize is the optional limit the stack buffer may grow to, after which stacks are truncated. If not
+	// given will default to DefaultPanicMaxStackBufferSize
+	MaxStackBufferSize *int
+	// GoroutineDumpDir optionally enables writing the stacks of all goroutines to a new file in this directory on every
+	// panic, creating the directory if required. Capturing all goroutines stops the world, so this is intended for
+	// debugging rather than steady state use
+	GoroutineDumpDir string
+	// Reporter optionally receives every recovered panic, for forwarding to an external error tracker
+	Reporter PanicReporter
+	// ReportRequest includes the request message in panic reports for unary calls. Consider whether requests may
+	// contain sensitive data before enabling
+	ReportRequest bool
+	// ErrorMode is the optional mode controlling the error returned to the client. If not given will default to
+	// PanicErrorModeMessage. Unrecognized modes are treated as PanicErrorModeOpaque, so nothing is leaked by a typo
+	ErrorMode PanicErrorMode
+	// IDGenerator is the optional generator for correlation IDs in PanicErrorModeOpaque when the request has no request
+	// ID. If not given will default to a ULIDGenerator
+	IDGenerator RequestIDGenerator
+	// Repanic re-raises the panic after it has been logged & reported, instead of returning an error to the client.
+	// Intended for crash-only deployments
+	Repanic bool
 }
 
 func NewPanicInterceptor(config PanicInterceptorConfig) *PanicInterceptor {
 	interceptor := &PanicInterceptor{
-		logger: config.Logger,
+		logger:        config.Logger,
+		reporter:      config.Reporter,
+		reportRequest: config.ReportRequest,
+		errorMode:     config.ErrorMode,
+		idGenerator:   config.IDGenerator,
+		repanic:       config.Repanic,
+		dumpDir:       config.GoroutineDumpDir,
+	}
+
+	switch interceptor.errorMode {
+	case PanicErrorModeMessage, PanicErrorModeOpaque, PanicErrorModeDevelopment:
+	default:
+		interceptor.errorMode = PanicErrorModeOpaque
+	}
+
+	if interceptor.idGenerator == nil {
+		interceptor.idGenerator = NewULIDGenerator()
 	}
 
 	size := config.StackBufferSize
@@ -34,53 +93,219 @@ func NewPanicInterceptor(config PanicInterceptorConfig) *PanicInterceptor {
 		interceptor.stackBufferSize = *size
 	}
 
+	maxSize := config.MaxStackBufferSize
+	if maxSize == nil {
+		interceptor.maxStackBufferSize = DefaultPanicMaxStackBufferSize
+	} else {
+		interceptor.maxStackBufferSize = *maxSize
+	}
+
 	return interceptor
 }
 
 var _ connect.Interceptor = (*PanicInterceptor)(nil)
 
+// PanicInterceptor recovers panics raised by handlers, logging them and returning a connect.CodeInternal error to the
+// client. Panics with http.ErrAbortHandler are not recovered, as they are used to intentionally abort a response.
+// Goroutines spawned by handlers can be given the same protection by starting them with Go.
 type PanicInterceptor struct {
 	unimplemented.UnimplementedInterceptor
-	logger          *logr.Logger
-	stackBufferSize int
+	logger             *logr.Logger
+	stackBufferSize    int
+	maxStackBufferSize int
+	reporter           PanicReporter
+	reportRequest      bool
+	errorMode          PanicErrorMode
+	idGenerator        RequestIDGenerator
+	repanic            bool
+	dumpDir            string
 }
 
 func (p *PanicInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (resp connect.AnyResponse, err error) {
-		log := p.getLogger(ctx)
+		var request any
+		if p.reportRequest {
+			request = req.Any()
+		}
+
+		guardCtx, guard := p.guardContext(ctx, req.Spec(), request)
+		defer guard.cancel(nil)
 
 		defer func() {
 			if r := recover(); r != nil {
-				stack := make([]byte, p.stackBufferSize)
-				stack = stack[:runtime.Stack(stack, false)]
-				log.Error(r.(error), "recovering from panic", "stack", string(stack))
-				err = connect.NewError(connect.CodeInternal, fmt.Errorf("recovering from panic: %v", r))
+				err = p.handlePanic(ctx, r, req.Spec(), request)
 			}
 		}()
 
-		resp, err = next(ctx, req)
-		return resp, err
+		resp, err = next(guardCtx, req)
+		return resp, guard.result(err)
 	})
 }
 
 func (p *PanicInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
 	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) (err error) {
-		log := p.getLogger(ctx)
+		guardCtx, guard := p.guardContext(ctx, conn.Spec(), nil)
+		defer guard.cancel(nil)
 
 		defer func() {
 			if r := recover(); r != nil {
-				stack := make([]byte, p.stackBufferSize)
-				stack = stack[:runtime.Stack(stack, false)]
-				log.Error(r.(error), "recovering from panic", "stack", string(stack))
-				err = connect.NewError(connect.CodeInternal, fmt.Errorf("recovering from panic: %v", r))
+				err = p.handlePanic(ctx, r, conn.Spec(), nil)
 			}
 		}()
 
-		err = next(ctx, conn)
-		return err
+		return guard.result(next(guardCtx, conn))
 	})
 }
 
+// handlePanic logs & reports the recovered panic value, returning the error to send to the client. It must be called
+// from the deferred function that recovered the panic, so the stack of the panicking goroutine is captured
+func (p *PanicInterceptor) handlePanic(ctx context.Context, r any, spec connect.Spec, request any) error {
+	// ErrAbortHandler is the sanctioned way to abort a response, and is expected to propagate to net/http
+	if r == http.ErrAbortHandler {
+		panic(r)
+	}
+
+	return p.recoverPanic(ctx, r, spec, request)
+}
+
+// recoverPanic is handlePanic without the special handling of http.ErrAbortHandler, for panics that net/http would
+// never see. The same requirement to be called from the recovering deferred function applies
+func (p *PanicInterceptor) recoverPanic(ctx context.Context, r any, spec connect.Spec, request any) error {
+	stack := captureStack(p.stackBufferSize, p.maxStackBufferSize, false)
+
+	log := p.getLogger(ctx)
+
+	var dumpPath string
+	if p.dumpDir != "" {
+		var err error
+		dumpPath, err = p.writeGoroutineDump()
+		if err != nil {
+			log.Error(err, "unable to write goroutine dump")
+		} else {
+			log = log.WithValues("goroutine-dump", dumpPath)
+		}
+	}
+
+	requestID, _ := RequestIDFromContext(ctx)
+
+	var panicID string
+	if p.errorMode == PanicErrorModeOpaque {
+		panicID = requestID
+		if panicID == "" {
+			panicID = p.idGenerator.NewRequestID()
+		}
+		log = log.WithValues("panic-id", panicID)
+	}
+
+	panicErr, kind := classifyPanic(r)
+	log.Error(panicErr, "recovering from panic", "panic-kind", kind, "panic-type", fmt.Sprintf("%T", r), "stack", string(stack))
+
+	if p.reporter != nil {
+		report := PanicReport{
+			ID:            panicID,
+			Time:          time.Now(),
+			Value:         r,
+			Stack:         stack,
+			Procedure:     spec.Procedure,
+			RequestID:     requestID,
+			Request:       request,
+			GoroutineDump: dumpPath,
+		}
+		if err := p.reporter.ReportPanic(ctx, report); err != nil {
+			log.Error(err, "unable to report panic")
+		}
+	}
+
+	if p.repanic {
+		panic(r)
+	}
+
+	return p.clientError(log, r, panicID, stack)
+}
+
+// clientError builds the error returned to the client according to the configured PanicErrorMode
+func (p *PanicInterceptor) clientError(log logr.Logger, r any, panicID string, stack []byte) error {
+	switch p.errorMode {
+	case PanicErrorModeOpaque:
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("internal error, reference: %v", panicID))
+	case PanicErrorModeDevelopment:
+		connectErr := connect.NewError(connect.CodeInternal, fmt.Errorf("recovering from panic: %v", r))
+		detail, err := connect.NewErrorDetail(&errdetails.DebugInfo{
+			StackEntries: strings.Split(strings.TrimSpace(string(stack)), "\n"),
+			Detail:       fmt.Sprintf("%v", r),
+		})
+		if err != nil {
+			log.Error(err, "unable to attach stack to panic error")
+			return connectErr
+		}
+		connectErr.AddDetail(detail)
+		return connectErr
+	default:
+		return connect.NewError(connect.CodeInternal, fmt.Errorf("recovering from panic: %v", r))
+	}
+}
+
+// captureStack returns the formatted stack of the calling goroutine, or all goroutines if all is set. The buffer starts
+// at size and is doubled until the stack fits, or it reaches maxSize
+func captureStack(size int, maxSize int, all bool) []byte {
+	if size <= 0 {
+		size = DefaultPanicStackBufferSize
+	}
+
+	for {
+		buf := make([]byte, size)
+		n := runtime.Stack(buf, all)
+		if n < size || size >= maxSize {
+			return buf[:n]
+		}
+		size = min(size*2, maxSize)
+	}
+}
+
+// writeGoroutineDump writes the stacks of all goroutines to a new file in the dump directory, returning its path
+func (p *PanicInterceptor) writeGoroutineDump() (string, error) {
+	dump := captureStack(p.stackBufferSize, p.maxStackBufferSize, true)
+
+	if err := os.MkdirAll(p.dumpDir, 0o755); err != nil {
+		return "", fmt.Errorf("error creating goroutine dump directory: %w", err)
+	}
+
+	f, err := os.CreateTemp(p.dumpDir, "goroutines-"+time.Now().UTC().Format("20060102T150405")+"-*.txt")
+	if err != nil {
+		return "", fmt.Errorf("error creating goroutine dump file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := f.Write(dump); err != nil {
+		return "", fmt.Errorf("error writing goroutine dump: %w", err)
+	}
+
+	return f.Name(), nil
+}
+
+const (
+	panicKindError    = "error"
+	panicKindString   = "string"
+	panicKindStringer = "stringer"
+	panicKindValue    = "value"
+)
+
+// classifyPanic converts an arbitrary recovered panic value into an error, along with the kind of value it was. Values
+// are only ever formatted through fmt, which recovers from panicking Error & String methods (including typed nils), so
+// a broken panic value cannot escape the recovery path
+func classifyPanic(r any) (error, string) {
+	switch v := r.(type) {
+	case error:
+		return fmt.Errorf("%w", v), panicKindError
+	case string:
+		return errors.New(v), panicKindString
+	case fmt.Stringer:
+		return errors.New(fmt.Sprint(v)), panicKindStringer
+	default:
+		return fmt.Errorf("%v", v), panicKindValue
+	}
+}
+
 func (p *PanicInterceptor) getLogger(ctx context.Context) logr.Logger {
 	if p.logger != nil {
 		return *p.logger
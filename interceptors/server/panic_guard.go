@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"sync"
+
+	"connectrpc.com/connect"
+)
+
+type panicGuardContextKey struct{}
+
+// panicGuard is attached to the handler context by the PanicInterceptor, allowing panics in goroutines spawned by the
+// handler to be recovered and to fail the call
+type panicGuard struct {
+	interceptor *PanicInterceptor
+	spec        connect.Spec
+	request     any
+	cancel      context.CancelCauseFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+// guardContext returns a copy of the context carrying a new guard, along with the guard itself. The returned context
+// is cancelled with the panic error when a goroutine started with Go panics
+func (p *PanicInterceptor) guardContext(ctx context.Context, spec connect.Spec, req any) (context.Context, *panicGuard) {
+	ctx, cancel := context.WithCancelCause(ctx)
+	guard := &panicGuard{
+		interceptor: p,
+		spec:        spec,
+		request:     req,
+		cancel:      cancel,
+	}
+	return context.WithValue(ctx, panicGuardContextKey{}, guard), guard
+}
+
+// fail records the first panic error and cancels the handler context with it
+func (g *panicGuard) fail(err error) {
+	g.mu.Lock()
+	if g.err == nil {
+		g.err = err
+	}
+	g.mu.Unlock()
+
+	g.cancel(err)
+}
+
+// result returns the panic error recorded by a goroutine, if any, otherwise the error returned by the handler
+func (g *panicGuard) result(err error) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if g.err != nil {
+		return g.err
+	}
+	return err
+}
+
+// Go runs fn in a new goroutine, with the same panic recovery as the PanicInterceptor handling the call. A panic in fn
+// is logged & reported, the context is cancelled, and the call fails with the resulting connect.CodeInternal error once
+// the handler returns. Handlers should wait for their goroutines to finish before returning, as the stream cannot be
+// used afterwards.
+//
+// A panic with http.ErrAbortHandler is treated like any other, as net/http cannot recover it from a goroutine it did not
+// start. If the PanicInterceptor is configured to Repanic, the panic is re-raised in the new goroutine, crashing the
+// process.
+//
+// If ctx was not created by a PanicInterceptor, fn is run without recovery.
+func Go(ctx context.Context, fn func()) {
+	guard, ok := ctx.Value(panicGuardContextKey{}).(*panicGuard)
+	if !ok {
+		go fn()
+		return
+	}
+
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				guard.fail(guard.interceptor.recoverPanic(ctx, r, guard.spec, guard.request))
+			}
+		}()
+
+		fn()
+	}()
+}
@@ -0,0 +1,109 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+func TestGo(t *testing.T) {
+	t.Parallel()
+
+	t.Run("streaming panic cancels the stream", func(t *testing.T) {
+		t.Parallel()
+
+		reporter := &recordingPanicReporter{}
+		inter := NewPanicInterceptor(PanicInterceptorConfig{Reporter: reporter})
+
+		stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			Go(ctx, func() {
+				panic("goroutine boom")
+			})
+
+			<-ctx.Done()
+			require.Equal(t, connect.CodeInternal, connect.CodeOf(context.Cause(ctx)))
+			return ctx.Err()
+		})
+
+		spec := connect.Spec{Procedure: "/pkg.v1.Service/Stream"}
+		err := stream(context.Background(), newTestStreamConn(spec.Procedure))
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+		require.ErrorContains(t, err, "goroutine boom")
+
+		require.Len(t, reporter.reports, 1)
+		require.Equal(t, "/pkg.v1.Service/Stream", reporter.reports[0].Procedure)
+		require.Contains(t, string(reporter.reports[0].Stack), "TestGo")
+	})
+
+	t.Run("abort handler cancels the stream", func(t *testing.T) {
+		t.Parallel()
+
+		inter := NewPanicInterceptor(PanicInterceptorConfig{})
+
+		stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			Go(ctx, func() {
+				panic(http.ErrAbortHandler)
+			})
+
+			<-ctx.Done()
+			return ctx.Err()
+		})
+
+		var err error
+		require.NotPanics(t, func() {
+			err = stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Stream"))
+		})
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+		require.ErrorContains(t, err, http.ErrAbortHandler.Error())
+	})
+
+	t.Run("unary panic fails the call", func(t *testing.T) {
+		t.Parallel()
+
+		inter := NewPanicInterceptor(PanicInterceptorConfig{})
+
+		unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			Go(ctx, func() {
+				panic("goroutine boom")
+			})
+
+			<-ctx.Done()
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		})
+
+		_, err := unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+	})
+
+	t.Run("no panic", func(t *testing.T) {
+		t.Parallel()
+
+		inter := NewPanicInterceptor(PanicInterceptorConfig{})
+
+		stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			wg := sync.WaitGroup{}
+			wg.Add(1)
+			Go(ctx, wg.Done)
+			wg.Wait()
+
+			return ctx.Err()
+		})
+
+		require.NoError(t, stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Stream")))
+	})
+
+	t.Run("without interceptor", func(t *testing.T) {
+		t.Parallel()
+
+		done := make(chan struct{})
+		Go(context.Background(), func() {
+			close(done)
+		})
+		<-done
+	})
+}
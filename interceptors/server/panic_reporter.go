@@ -0,0 +1,213 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+const (
+	DefaultWebhookPanicReporterTimeout = 5 * time.Second
+)
+
+// PanicReport describes a panic recovered by the PanicInterceptor
+type PanicReport struct {
+	// ID is the correlation ID returned to the client, only populated when using PanicErrorModeOpaque
+	ID   string
+	Time time.Time
+	// Value is the raw value passed to panic
+	Value any
+	// Stack is the stack of the panicking goroutine
+	Stack []byte
+	// Procedure is the procedure being handled when the panic occurred
+	Procedure string
+	// RequestID is the request ID attached by the ContextLoggerInterceptor, if any
+	RequestID string
+	// Request is the request message, only populated for unary calls when the interceptor is configured to report it
+	Request any
+	// GoroutineDump is the path of the file containing the stacks of all goroutines, when the interceptor is configured
+	// with a GoroutineDumpDir
+	GoroutineDump string
+}
+
+// PanicReporter forwards recovered panics to an external system, such as an error tracker. Implementations must be safe
+// for concurrent use
+type PanicReporter interface {
+	ReportPanic(ctx context.Context, report PanicReport) error
+}
+
+var (
+	_ PanicReporter = (*JSONLinesPanicReporter)(nil)
+	_ PanicReporter = (*WebhookPanicReporter)(nil)
+)
+
+// panicReportJSON is the JSON representation of a PanicReport shared by the bundled reporters
+type panicReportJSON struct {
+	ID            string          `json:"id,omitempty"`
+	Time          string          `json:"time"`
+	Value         string          `json:"value"`
+	Kind          string          `json:"kind"`
+	Type          string          `json:"type"`
+	Stack         string          `json:"stack"`
+	Procedure     string          `json:"procedure,omitempty"`
+	RequestID     string          `json:"request_id,omitempty"`
+	Request       json.RawMessage `json:"request,omitempty"`
+	GoroutineDump string          `json:"goroutine_dump,omitempty"`
+}
+
+func marshalPanicReport(report PanicReport) ([]byte, error) {
+	panicErr, kind := classifyPanic(report.Value)
+
+	out := panicReportJSON{
+		ID:            report.ID,
+		Time:          report.Time.UTC().Format(time.RFC3339Nano),
+		Value:         panicErr.Error(),
+		Kind:          kind,
+		Type:          fmt.Sprintf("%T", report.Value),
+		Stack:         string(report.Stack),
+		Procedure:     report.Procedure,
+		RequestID:     report.RequestID,
+		GoroutineDump: report.GoroutineDump,
+	}
+
+	if objProto, ok := report.Request.(protoreflect.ProtoMessage); ok {
+		request, err := protojson.Marshal(objProto)
+		if err != nil {
+			return nil, fmt.Errorf("error marshalling panic request with protojson: %w", err)
+		}
+		out.Request = request
+	}
+
+	return json.Marshal(out)
+}
+
+// NewJSONLinesPanicReporter returns a reporter writing each panic as a single line JSON object to the given writer
+func NewJSONLinesPanicReporter(w io.Writer) *JSONLinesPanicReporter {
+	return &JSONLinesPanicReporter{
+		w: w,
+	}
+}
+
+// NewJSONLinesFilePanicReporter returns a JSONLinesPanicReporter appending to the file at path, creating it if required.
+// The returned reporter should be closed once no longer in use
+func NewJSONLinesFilePanicReporter(path string) (*JSONLinesPanicReporter, error) {
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("error opening panic report file: %w", err)
+	}
+
+	return &JSONLinesPanicReporter{
+		w:      f,
+		closer: f,
+	}, nil
+}
+
+type JSONLinesPanicReporter struct {
+	mu     sync.Mutex
+	w      io.Writer
+	closer io.Closer
+}
+
+func (j *JSONLinesPanicReporter) ReportPanic(_ context.Context, report PanicReport) error {
+	line, err := marshalPanicReport(report)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	_, err = j.w.Write(append(line, '\n'))
+	return err
+}
+
+// Close closes the underlying file, if the reporter was created with NewJSONLinesFilePanicReporter
+func (j *JSONLinesPanicReporter) Close() error {
+	if j.closer != nil {
+		return j.closer.Close()
+	}
+	return nil
+}
+
+type WebhookPanicReporterConfig struct {
+	// URL is the endpoint panic reports are POSTed to as JSON
+	URL string
+	// Client is the optional HTTP client used to send reports. If not given will default to http.DefaultClient
+	Client *http.Client
+	// Timeout optionally bounds how long sending a single report may take, regardless of any timeout on Client. As
+	// reports are sent from the recovery path, this also bounds how long a panicking call is held up. If not given will
+	// default to DefaultWebhookPanicReporterTimeout
+	Timeout time.Duration
+	// Header is optional additional headers sent with every report, such as authorization
+	Header http.Header
+}
+
+// NewWebhookPanicReporter returns a reporter POSTing each panic as a JSON object to the configured URL
+func NewWebhookPanicReporter(config WebhookPanicReporterConfig) *WebhookPanicReporter {
+	client := config.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+
+	timeout := config.Timeout
+	if timeout <= 0 {
+		timeout = DefaultWebhookPanicReporterTimeout
+	}
+
+	return &WebhookPanicReporter{
+		url:     config.URL,
+		client:  client,
+		header:  config.Header,
+		timeout: timeout,
+	}
+}
+
+type WebhookPanicReporter struct {
+	url     string
+	client  *http.Client
+	header  http.Header
+	timeout time.Duration
+}
+
+func (w *WebhookPanicReporter) ReportPanic(ctx context.Context, report PanicReport) error {
+	body, err := marshalPanicReport(report)
+	if err != nil {
+		return err
+	}
+
+	// The request context is likely already cancelled or about to be, reports should still be delivered, but never hold
+	// up the panicking call for longer than the timeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), w.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error building panic report request: %w", err)
+	}
+	for name, values := range w.header {
+		req.Header[name] = values
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("error sending panic report: %w", err)
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("panic report webhook returned status %v", resp.StatusCode)
+	}
+
+	return nil
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// recordingPanicReporter collects reports for assertions
+type recordingPanicReporter struct {
+	mu      sync.Mutex
+	reports []PanicReport
+}
+
+func (r *recordingPanicReporter) ReportPanic(_ context.Context, report PanicReport) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.reports = append(r.reports, report)
+	return nil
+}
+
+func TestPanicInterceptorReporter(t *testing.T) {
+	t.Parallel()
+
+	reporter := &recordingPanicReporter{}
+	inter := NewPanicInterceptor(PanicInterceptorConfig{
+		Reporter:      reporter,
+		ReportRequest: true,
+	})
+
+	unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		panic("boom")
+	})
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	_, err := unary(ctx, connect.NewRequest(wrapperspb.String("input")))
+	require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+
+	require.Len(t, reporter.reports, 1)
+	report := reporter.reports[0]
+	require.Equal(t, "boom", report.Value)
+	require.Equal(t, "req-1", report.RequestID)
+	require.Equal(t, "input", report.Request.(*wrapperspb.StringValue).GetValue())
+	require.Contains(t, string(report.Stack), "TestPanicInterceptorReporter")
+}
+
+func TestJSONLinesPanicReporter(t *testing.T) {
+	t.Parallel()
+
+	buf := &bytes.Buffer{}
+	reporter := NewJSONLinesPanicReporter(buf)
+
+	require.NoError(t, reporter.ReportPanic(context.Background(), PanicReport{
+		Time:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Value:     42,
+		Stack:     []byte("stack"),
+		Procedure: "/pkg.v1.Service/Get",
+		RequestID: "req-1",
+		Request:   wrapperspb.String("input"),
+	}))
+
+	require.JSONEq(t, `{
+		"time": "2024-01-02T03:04:05Z",
+		"value": "42",
+		"kind": "value",
+		"type": "int",
+		"stack": "stack",
+		"procedure": "/pkg.v1.Service/Get",
+		"request_id": "req-1",
+		"request": "input"
+	}`, buf.String())
+}
+
+func TestMarshalPanicReportBrokenValues(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name          string
+		value         any
+		expectedValue string
+		expectedKind  string
+	}{
+		{
+			name:          "panicking stringer",
+			value:         brokenStringer{},
+			expectedValue: "%!v(PANIC=String method: broken stringer)",
+			expectedKind:  panicKindStringer,
+		},
+		{
+			name:          "typed nil error",
+			value:         (*nilError)(nil),
+			expectedValue: "<nil>",
+			expectedKind:  panicKindError,
+		},
+	}
+
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var out []byte
+			require.NotPanics(t, func() {
+				var err error
+				out, err = marshalPanicReport(PanicReport{Value: tc.value})
+				require.NoError(t, err)
+			})
+
+			report := map[string]any{}
+			require.NoError(t, json.Unmarshal(out, &report))
+			require.Equal(t, tc.expectedValue, report["value"])
+			require.Equal(t, tc.expectedKind, report["kind"])
+		})
+	}
+}
+
+func TestWebhookPanicReporter(t *testing.T) {
+	t.Parallel()
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		received := make(chan map[string]any, 1)
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			require.Equal(t, http.MethodPost, r.Method)
+			require.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			require.Equal(t, "Bearer token", r.Header.Get("Authorization"))
+
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+
+			obj := map[string]any{}
+			require.NoError(t, json.Unmarshal(body, &obj))
+			received <- obj
+
+			w.WriteHeader(http.StatusNoContent)
+		}))
+		t.Cleanup(srv.Close)
+
+		reporter := NewWebhookPanicReporter(WebhookPanicReporterConfig{
+			URL:    srv.URL,
+			Header: http.Header{"Authorization": {"Bearer token"}},
+		})
+
+		require.NoError(t, reporter.ReportPanic(context.Background(), PanicReport{
+			Time:      time.Now(),
+			Value:     "boom",
+			Procedure: "/pkg.v1.Service/Get",
+		}))
+
+		obj := <-received
+		require.Equal(t, "boom", obj["value"])
+		require.Equal(t, "string", obj["kind"])
+		require.Equal(t, "/pkg.v1.Service/Get", obj["procedure"])
+	})
+
+	t.Run("error status", func(t *testing.T) {
+		t.Parallel()
+
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusInternalServerError)
+		}))
+		t.Cleanup(srv.Close)
+
+		reporter := NewWebhookPanicReporter(WebhookPanicReporterConfig{URL: srv.URL})
+		require.Error(t, reporter.ReportPanic(context.Background(), PanicReport{Value: "boom"}))
+	})
+
+	t.Run("timeout without client timeout", func(t *testing.T) {
+		t.Parallel()
+
+		release := make(chan struct{})
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			<-release
+		}))
+		t.Cleanup(srv.Close)
+		t.Cleanup(func() { close(release) })
+
+		reporter := NewWebhookPanicReporter(WebhookPanicReporterConfig{
+			URL:     srv.URL,
+			Client:  &http.Client{},
+			Timeout: 50 * time.Millisecond,
+		})
+
+		start := time.Now()
+		err := reporter.ReportPanic(context.Background(), PanicReport{Value: "boom"})
+		require.ErrorIs(t, err, context.DeadlineExceeded)
+		require.Less(t, time.Since(start), 5*time.Second)
+	})
+}
@@ -0,0 +1,284 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+type panicStringer struct{}
+
+func (panicStringer) String() string { return "stringer boom" }
+
+type brokenStringer struct{}
+
+func (brokenStringer) String() string { panic("broken stringer") }
+
+type nilError struct {
+	msg string
+}
+
+func (e *nilError) Error() string { return e.msg }
+
+type panicStruct struct {
+	Code int
+}
+
+func TestPanicInterceptor(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name         string
+		value        any
+		expectedKind string
+		expectedMsg  string
+	}{
+		{
+			name:         "error",
+			value:        errors.New("error boom"),
+			expectedKind: panicKindError,
+			expectedMsg:  "error boom",
+		},
+		{
+			name:         "string",
+			value:        "string boom",
+			expectedKind: panicKindString,
+			expectedMsg:  "string boom",
+		},
+		{
+			name:         "stringer",
+			value:        panicStringer{},
+			expectedKind: panicKindStringer,
+			expectedMsg:  "stringer boom",
+		},
+		{
+			name:         "panicking stringer",
+			value:        brokenStringer{},
+			expectedKind: panicKindStringer,
+			expectedMsg:  "%!v(PANIC=String method: broken stringer)",
+		},
+		{
+			name:         "typed nil error",
+			value:        (*nilError)(nil),
+			expectedKind: panicKindError,
+			expectedMsg:  "<nil>",
+		},
+		{
+			name:         "int",
+			value:        42,
+			expectedKind: panicKindValue,
+			expectedMsg:  "42",
+		},
+		{
+			name:         "struct",
+			value:        panicStruct{Code: 7},
+			expectedKind: panicKindValue,
+			expectedMsg:  "{7}",
+		},
+	}
+
+	paths := []struct {
+		name string
+		call func(inter *PanicInterceptor, value any) error
+	}{
+		{
+			name: "unary",
+			call: func(inter *PanicInterceptor, value any) error {
+				unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+					panic(value)
+				})
+				_, err := unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+				return err
+			},
+		},
+		{
+			name: "streaming",
+			call: func(inter *PanicInterceptor, value any) error {
+				stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+					panic(value)
+				})
+				return stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Stream"))
+			},
+		},
+	}
+
+	for _, path := range paths {
+		for _, tc := range testData {
+			t.Run(path.name+" "+tc.name, func(t *testing.T) {
+				t.Parallel()
+
+				lines := []map[string]any{}
+				log := funcr.NewJSON(func(obj string) {
+					line := map[string]any{}
+					require.NoError(t, json.Unmarshal([]byte(obj), &line))
+					lines = append(lines, line)
+				}, funcr.Options{})
+
+				inter := NewPanicInterceptor(PanicInterceptorConfig{Logger: &log})
+
+				var err error
+				require.NotPanics(t, func() {
+					err = path.call(inter, tc.value)
+				})
+
+				require.Error(t, err)
+				require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+
+				require.Len(t, lines, 1)
+				require.Equal(t, "recovering from panic", lines[0]["msg"])
+				require.Equal(t, tc.expectedMsg, lines[0]["error"])
+				require.Equal(t, tc.expectedKind, lines[0]["panic-kind"])
+				require.NotEmpty(t, lines[0]["stack"])
+			})
+		}
+
+		t.Run(path.name+" abort handler", func(t *testing.T) {
+			t.Parallel()
+
+			inter := NewPanicInterceptor(PanicInterceptorConfig{})
+			require.PanicsWithValue(t, http.ErrAbortHandler, func() {
+				_ = path.call(inter, http.ErrAbortHandler)
+			})
+		})
+	}
+}
+
+func TestPanicInterceptorErrorMode(t *testing.T) {
+	t.Parallel()
+
+	call := func(inter *PanicInterceptor, ctx context.Context) error {
+		unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			panic("secret boom")
+		})
+		_, err := unary(ctx, connect.NewRequest(&emptypb.Empty{}))
+		return err
+	}
+
+	t.Run("message", func(t *testing.T) {
+		t.Parallel()
+
+		err := call(NewPanicInterceptor(PanicInterceptorConfig{}), context.Background())
+		require.Equal(t, "recovering from panic: secret boom", connectErrorMessage(t, err))
+	})
+
+	t.Run("opaque with request id", func(t *testing.T) {
+		t.Parallel()
+
+		inter := NewPanicInterceptor(PanicInterceptorConfig{ErrorMode: PanicErrorModeOpaque})
+		err := call(inter, ContextWithRequestID(context.Background(), "req-1"))
+		require.Equal(t, "internal error, reference: req-1", connectErrorMessage(t, err))
+	})
+
+	t.Run("opaque generated id", func(t *testing.T) {
+		t.Parallel()
+
+		reporter := &recordingPanicReporter{}
+		inter := NewPanicInterceptor(PanicInterceptorConfig{
+			ErrorMode:   PanicErrorModeOpaque,
+			IDGenerator: NewSequenceGenerator("panic"),
+			Reporter:    reporter,
+		})
+		err := call(inter, context.Background())
+		require.Equal(t, "internal error, reference: panic-1", connectErrorMessage(t, err))
+		require.Len(t, reporter.reports, 1)
+		require.Equal(t, "panic-1", reporter.reports[0].ID)
+	})
+
+	t.Run("unknown mode is opaque", func(t *testing.T) {
+		t.Parallel()
+
+		inter := NewPanicInterceptor(PanicInterceptorConfig{ErrorMode: "bogus"})
+		err := call(inter, ContextWithRequestID(context.Background(), "req-1"))
+		require.NotContains(t, connectErrorMessage(t, err), "secret")
+	})
+
+	t.Run("development", func(t *testing.T) {
+		t.Parallel()
+
+		inter := NewPanicInterceptor(PanicInterceptorConfig{ErrorMode: PanicErrorModeDevelopment})
+		err := call(inter, context.Background())
+
+		connectErr := &connect.Error{}
+		require.ErrorAs(t, err, &connectErr)
+		require.Len(t, connectErr.Details(), 1)
+
+		value, err := connectErr.Details()[0].Value()
+		require.NoError(t, err)
+		debugInfo, ok := value.(*errdetails.DebugInfo)
+		require.True(t, ok)
+		require.Equal(t, "secret boom", debugInfo.GetDetail())
+		require.NotEmpty(t, debugInfo.GetStackEntries())
+	})
+
+	t.Run("repanic", func(t *testing.T) {
+		t.Parallel()
+
+		reporter := &recordingPanicReporter{}
+		inter := NewPanicInterceptor(PanicInterceptorConfig{Repanic: true, Reporter: reporter})
+		require.PanicsWithValue(t, "secret boom", func() {
+			_ = call(inter, context.Background())
+		})
+		require.Len(t, reporter.reports, 1)
+	})
+}
+
+func connectErrorMessage(t *testing.T, err error) string {
+	t.Helper()
+
+	connectErr := &connect.Error{}
+	require.ErrorAs(t, err, &connectErr)
+	return connectErr.Message()
+}
+
+func TestCaptureStack(t *testing.T) {
+	t.Parallel()
+
+	t.Run("grows until the stack fits", func(t *testing.T) {
+		t.Parallel()
+
+		stack := captureStack(16, DefaultPanicMaxStackBufferSize, false)
+		require.Greater(t, len(stack), 16)
+		require.Contains(t, string(stack), "TestCaptureStack")
+	})
+
+	t.Run("truncates at max size", func(t *testing.T) {
+		t.Parallel()
+
+		stack := captureStack(16, 64, false)
+		require.Len(t, stack, 64)
+	})
+}
+
+func TestPanicInterceptorGoroutineDump(t *testing.T) {
+	t.Parallel()
+
+	dir := filepath.Join(t.TempDir(), "dumps")
+	reporter := &recordingPanicReporter{}
+	inter := NewPanicInterceptor(PanicInterceptorConfig{
+		GoroutineDumpDir: dir,
+		Reporter:         reporter,
+	})
+
+	stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		panic("boom")
+	})
+	require.Error(t, stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Stream")))
+
+	require.Len(t, reporter.reports, 1)
+	dumpPath := reporter.reports[0].GoroutineDump
+	require.Equal(t, dir, filepath.Dir(dumpPath))
+
+	dump, err := os.ReadFile(dumpPath)
+	require.NoError(t, err)
+	require.Contains(t, string(dump), "TestPanicInterceptorGoroutineDump")
+}
@@ -2,17 +2,25 @@ package server
 
 import (
 	"context"
-	"fmt"
-	"strings"
+	"crypto/subtle"
+	"net/http"
 
 	"connectrpc.com/connect"
 	"github.com/go-logr/logr"
+	"github.com/nicjohnson145/connecthelp/interceptors/matcher"
+	"github.com/nicjohnson145/connecthelp/interceptors/payload"
 	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
-	"github.com/nicjohnson145/hlp/set"
-	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
+const (
+	DefaultErrorRequestBufferSize = 10
+)
+
+// DefaultForceLogRateLimit is the default limit on payloads logged for calls forced via the ForceLogHeader
+var DefaultForceLogRateLimit = RateLimit{PerSecond: 1, Burst: 10}
+
 type PayloadLoggingInterceptorConfig struct {
 	// Logger is the optional logger the method calls will be logged with, if not given, will attempt to use the context
 	// logger. If neither are present, no logging will be done
@@ -23,94 +31,307 @@ type PayloadLoggingInterceptorConfig struct {
 	// ResponseMethods is a comma separated list of methods to log responses for. The special value of '*' means log all
 	// response payloads
 	ResponseMethods string
-	// Pretty eschew's the provided logger & context logger, and instead prints the output with fmt.Println ad a
+	// RequestMatcher optionally selects the methods to log requests for, taking precedence over RequestMethods
+	RequestMatcher *matcher.MethodMatcher
+	// ResponseMatcher optionally selects the methods to log responses for, taking precedence over ResponseMethods
+	ResponseMatcher *matcher.MethodMatcher
+	// Pretty eschew's the provided logger & context logger, and instead prints the output to stdout as a
 	// human-readable indented object. Mostly intended for development debugging where log aggregators maybe not be in
-	// play
+	// play. Combine with a Sink to print somewhere other than stdout
 	Pretty bool
+	// RedactFields optionally lists fields to redact, keyed by message full name (e.g. pkg.v1.LoginRequest) with field
+	// paths relative to that message (e.g. password, or credentials.token). Fields marked with the standard debug_redact
+	// option are always redacted. Redacted string & bytes fields are replaced with RedactionPlaceholder, fields of any
+	// other kind are cleared
+	RedactFields map[string][]string
+	// RedactExtension is an optional custom field option extension marking fields to redact. Boolean extensions redact
+	// when set to true, extensions of any other type redact when present
+	RedactExtension protoreflect.ExtensionType
+	// RedactionPlaceholder is the optional value redacted fields are replaced with. If not given will default to
+	// payload.DefaultRedactionPlaceholder
+	RedactionPlaceholder string
+	// MaxPayloadSize optionally limits the total size in bytes of the formatted payload, counting both keys & values.
+	// Larger payloads are cut off and annotated with the number of bytes elided. For formatters logging multiple
+	// fields, pairs that no longer fit are dropped, with the number dropped logged under the "elided-pairs" key
+	MaxPayloadSize int
+	// MaxStringLength optionally limits the length in bytes of each string field
+	MaxStringLength int
+	// MaxBytesLength optionally limits the length of each bytes field
+	MaxBytesLength int
+	// MaxRepeatedElements optionally limits the number of elements logged for each repeated field
+	MaxRepeatedElements int
+	// ErrorRequestMethods is a comma separated list of methods to log requests for only when the call fails. The special
+	// value of '*' means all methods. Requests already logged via RequestMethods are not logged again
+	ErrorRequestMethods string
+	// ErrorRequestMatcher optionally selects the methods to log requests for only when the call fails, taking
+	// precedence over ErrorRequestMethods
+	ErrorRequestMatcher *matcher.MethodMatcher
+	// ErrorCodes optionally restricts failure request logging to errors with the given codes. If not given, requests
+	// are logged for all errors
+	ErrorCodes []connect.Code
+	// ErrorRequestBufferSize is the optional number of most recently received stream messages kept for failure request
+	// logging. If not given will default to DefaultErrorRequestBufferSize
+	ErrorRequestBufferSize *int
+	// SampleRate optionally logs payloads for only 1 in SampleRate calls. Failure request logging is not sampled
+	SampleRate int
+	// RateLimit optionally limits how often payloads are logged for each procedure
+	RateLimit *RateLimit
+	// ProcedureRateLimits optionally overrides RateLimit for specific procedures, keyed by the full procedure name
+	ProcedureRateLimits map[string]RateLimit
+	// ForceLogHeader is an optional request header that, when present, logs the request & response payloads of the
+	// call regardless of the configured methods, sampling and rate limits. As the header is controlled by the caller,
+	// forced payloads are subject to ForceLogRateLimit instead
+	ForceLogHeader string
+	// ForceLogHeaderValue is an optional secret the ForceLogHeader must be set to for logging to be forced. If not
+	// given, any non-empty value forces logging
+	ForceLogHeaderValue string
+	// ForceLogRateLimit optionally limits how often payloads are logged for forced calls, shared across all
+	// procedures. If not given will default to DefaultForceLogRateLimit
+	ForceLogRateLimit *RateLimit
+	// Formatter optionally controls how payloads are rendered. If not given will default to a
+	// payload.ProtoJSONFormatter, indented when Pretty is set
+	Formatter payload.Formatter
+	// Headers optionally logs request headers alongside request payloads, and response headers & trailers alongside
+	// response payloads. For streams, headers are logged with the first message in each direction, and trailers are
+	// not logged as they are not available until the stream completes
+	Headers *HeaderLogging
+	// Sink optionally receives payloads in place of the provided logger & context logger, allowing payloads to be
+	// captured separately from application logs. If not given and Pretty is set, will default to a payload.WriterSink on
+	// stdout
+	Sink payload.Sink
 }
 
 func NewPayloadLoggingInterceptor(config PayloadLoggingInterceptorConfig) *PayloadLoggingInterceptor {
-	toFilter := func(str string) methodLoggingFilter {
-		switch str {
-		case "":
-			return func(s string) bool { return false }
-		case "*":
-			return func(s string) bool { return true }
-		default:
-			methodSet := set.New(strings.Split(str, ",")...)
-			return func(s string) bool { return methodSet.Contains(s) }
-		}
+	requestMatcher := config.RequestMatcher
+	if requestMatcher == nil {
+		requestMatcher = matcher.FromMethodList(config.RequestMethods)
+	}
+	responseMatcher := config.ResponseMatcher
+	if responseMatcher == nil {
+		responseMatcher = matcher.FromMethodList(config.ResponseMethods)
+	}
+	errorRequestMatcher := config.ErrorRequestMatcher
+	if errorRequestMatcher == nil {
+		errorRequestMatcher = matcher.FromMethodList(config.ErrorRequestMethods)
+	}
+
+	errorCodes := make(map[connect.Code]struct{}, len(config.ErrorCodes))
+	for _, code := range config.ErrorCodes {
+		errorCodes[code] = struct{}{}
+	}
+
+	errorRequestBufferSize := DefaultErrorRequestBufferSize
+	if config.ErrorRequestBufferSize != nil {
+		errorRequestBufferSize = *config.ErrorRequestBufferSize
+	}
+
+	forceLogRateLimit := DefaultForceLogRateLimit
+	if config.ForceLogRateLimit != nil {
+		forceLogRateLimit = *config.ForceLogRateLimit
 	}
 
 	return &PayloadLoggingInterceptor{
-		logger:         config.Logger,
-		requestFilter:  toFilter(config.RequestMethods),
-		responseFilter: toFilter(config.ResponseMethods),
-		pretty:         config.Pretty,
+		logger:          config.Logger,
+		requestMatcher:  requestMatcher,
+		responseMatcher: responseMatcher,
+		payloadLogger: payload.NewLogger(payload.LoggerConfig{
+			Pretty:               config.Pretty,
+			RedactFields:         config.RedactFields,
+			RedactExtension:      config.RedactExtension,
+			RedactionPlaceholder: config.RedactionPlaceholder,
+			MaxPayloadSize:       config.MaxPayloadSize,
+			MaxStringLength:      config.MaxStringLength,
+			MaxBytesLength:       config.MaxBytesLength,
+			MaxRepeatedElements:  config.MaxRepeatedElements,
+			Formatter:            config.Formatter,
+			Sink:                 config.Sink,
+		}),
+		errorRequestMatcher:    errorRequestMatcher,
+		errorCodes:             errorCodes,
+		errorRequestBufferSize: errorRequestBufferSize,
+		sampleRate:             config.SampleRate,
+		sampled:                sampled,
+		limiter:                newRateLimiter(config.RateLimit, config.ProcedureRateLimits),
+		forceLogHeader:         config.ForceLogHeader,
+		forceLogHeaderValue:    config.ForceLogHeaderValue,
+		forceLimiter:           newRateLimiter(&forceLogRateLimit, nil),
+		headers:                newHeaderLogger(config.Headers),
 	}
 }
 
 var _ connect.Interceptor = (*PayloadLoggingInterceptor)(nil)
 
-type methodLoggingFilter func(string) bool
-
 type PayloadLoggingInterceptor struct {
 	unimplemented.UnimplementedInterceptor
 	logger *logr.Logger
 
-	requestFilter  methodLoggingFilter
-	responseFilter methodLoggingFilter
-	pretty         bool
+	requestMatcher  *matcher.MethodMatcher
+	responseMatcher *matcher.MethodMatcher
+	payloadLogger   *payload.Logger
+
+	errorRequestMatcher    *matcher.MethodMatcher
+	errorCodes             map[connect.Code]struct{}
+	errorRequestBufferSize int
+
+	sampleRate          int
+	sampled             func(rate int) bool
+	limiter             *rateLimiter
+	forceLogHeader      string
+	forceLogHeaderValue string
+	forceLimiter        *rateLimiter
+	headers             *headerLogger
+}
+
+// forceLogRateLimitKey is the single bucket forced payloads are limited by, regardless of procedure
+const forceLogRateLimitKey = ""
+
+// payloadLogPlan is the decision of what to log for a single call
+type payloadLogPlan struct {
+	procedure    string
+	logRequests  bool
+	logResponses bool
+	// bufferRequests keeps received requests, to be logged if the handler fails
+	bufferRequests bool
+	// forced plans bypass sampling & the procedure rate limits, and are subject to the force rate limit instead
+	forced bool
+}
+
+func (p payloadLogPlan) empty() bool {
+	return !p.logRequests && !p.logResponses && !p.bufferRequests
 }
 
 func (p *PayloadLoggingInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
 		log := p.getLogger(ctx)
+		plan := p.plan(req.Spec(), req.Header())
 
-		maybeLog := func(filter methodLoggingFilter, method string, obj any, objType string) {
-			if !filter(method) {
-				return
-			}
+		if plan.logRequests {
+			p.emit(plan, log, req.Any(), "request object", p.headers.requestValues(req.Header())...)
+		}
 
-			objProto, ok := obj.(protoreflect.ProtoMessage)
-			if ok {
-				opts := protojson.MarshalOptions{}
-				if p.pretty {
-					opts.Indent = "    "
-				}
-
-				out, err := opts.Marshal(objProto)
-				if err != nil {
-					log.Error(err, fmt.Sprintf("unable to marshal object using protojson, cannot log %v", objType))
-					return
-				}
-
-				if p.pretty {
-					fmt.Println("\n" + string(out))
-				} else {
-					log.Info(objType, "object", string(out))
-				}
-			}
+		// Capture the request as received, in case the handler modifies it before failing
+		var buffered any
+		if plan.bufferRequests {
+			buffered = cloneMessage(req.Any())
 		}
 
-		maybeLog(p.requestFilter, req.Spec().Procedure, req.Any(), "request object")
 		resp, err := next(ctx, req)
-		if err == nil {
-			maybeLog(p.responseFilter, req.Spec().Procedure, resp.Any(), "response object")
+		if err == nil && plan.logResponses {
+			p.emit(plan, log, resp.Any(), "response object", p.headers.responseValues(resp.Header(), resp.Trailer())...)
+		}
+		if buffered != nil && p.shouldLogFailure(err) {
+			p.emit(plan, log, buffered, "failed request object", append(
+				[]any{"error", err.Error(), "code", codeString(err)},
+				p.headers.requestValues(req.Header())...,
+			)...)
 		}
 
 		return resp, err
 	})
 }
 
-// TODO: implement streaming. Probably implmenting the StreamingHandlerConn interface, and "peeking" the initial client
-// request, and then logging the responses in the overwritten `Send` method
 func (p *PayloadLoggingInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
 	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
-		return next(ctx, conn)
+		plan := p.plan(conn.Spec(), conn.RequestHeader())
+		if plan.empty() {
+			return next(ctx, conn)
+		}
+
+		loggingConn := &payloadLoggingHandlerConn{
+			StreamingHandlerConn: conn,
+			interceptor:          p,
+			log:                  p.getLogger(ctx),
+			plan:                 plan,
+		}
+
+		err := next(ctx, loggingConn)
+		if plan.bufferRequests && p.shouldLogFailure(err) {
+			for _, buffered := range loggingConn.buffered {
+				p.emit(
+					plan, loggingConn.log, buffered.msg, "failed request object",
+					"direction", streamDirectionReceive, "index", buffered.index, "error", err.Error(), "code", codeString(err),
+				)
+			}
+		}
+
+		return err
 	})
 }
 
+// plan decides what should be logged for a call
+func (p *PayloadLoggingInterceptor) plan(spec connect.Spec, header http.Header) payloadLogPlan {
+	if p.forced(header) {
+		return payloadLogPlan{
+			procedure:    spec.Procedure,
+			logRequests:  true,
+			logResponses: true,
+			forced:       true,
+		}
+	}
+
+	plan := payloadLogPlan{
+		procedure:    spec.Procedure,
+		logRequests:  p.requestMatcher.MatchesSpec(spec),
+		logResponses: p.responseMatcher.MatchesSpec(spec),
+	}
+	plan.bufferRequests = !plan.logRequests && p.errorRequestBufferSize > 0 && p.errorRequestMatcher.MatchesSpec(spec)
+
+	if (plan.logRequests || plan.logResponses) && !p.sampled(p.sampleRate) {
+		plan.logRequests = false
+		plan.logResponses = false
+		// Requests not logged up front due to sampling should still be available if the call fails
+		plan.bufferRequests = p.errorRequestBufferSize > 0 && p.errorRequestMatcher.MatchesSpec(spec)
+	}
+
+	return plan
+}
+
+// forced reports whether the request headers force payload logging
+func (p *PayloadLoggingInterceptor) forced(header http.Header) bool {
+	if p.forceLogHeader == "" {
+		return false
+	}
+
+	value := header.Get(p.forceLogHeader)
+	if p.forceLogHeaderValue == "" {
+		return value != ""
+	}
+	return subtle.ConstantTimeCompare([]byte(value), []byte(p.forceLogHeaderValue)) == 1
+}
+
+// emit logs the payload, subject to the procedures rate limit, or the force rate limit for forced plans
+func (p *PayloadLoggingInterceptor) emit(plan payloadLogPlan, log logr.Logger, obj any, objType string, keysAndValues ...any) {
+	if plan.forced {
+		if !p.forceLimiter.allow(forceLogRateLimitKey) {
+			return
+		}
+	} else if !p.limiter.allow(plan.procedure) {
+		return
+	}
+	p.payloadLogger.Log(log, obj, objType, keysAndValues...)
+}
+
+// shouldLogFailure reports whether buffered requests should be logged for the given handler error
+func (p *PayloadLoggingInterceptor) shouldLogFailure(err error) bool {
+	if err == nil {
+		return false
+	}
+	if len(p.errorCodes) == 0 {
+		return true
+	}
+	_, ok := p.errorCodes[connect.CodeOf(err)]
+	return ok
+}
+
+// cloneMessage returns a copy of the given object if it is a proto message, otherwise nil
+func cloneMessage(obj any) any {
+	objProto, ok := obj.(protoreflect.ProtoMessage)
+	if !ok {
+		return nil
+	}
+	return proto.Clone(objProto)
+}
+
 func (p *PayloadLoggingInterceptor) getLogger(ctx context.Context) logr.Logger {
 	if p.logger != nil {
 		return *p.logger
@@ -118,3 +339,64 @@ func (p *PayloadLoggingInterceptor) getLogger(ctx context.Context) logr.Logger {
 		return logr.FromContextOrDiscard(ctx)
 	}
 }
+
+const (
+	streamDirectionReceive = "receive"
+	streamDirectionSend    = "send"
+)
+
+// payloadLoggingHandlerConn wraps a streaming connection, logging each message as it is received from the client or
+// sent to the client
+type payloadLoggingHandlerConn struct {
+	connect.StreamingHandlerConn
+	interceptor  *PayloadLoggingInterceptor
+	log          logr.Logger
+	plan         payloadLogPlan
+	receiveIndex int
+	sendIndex    int
+	// buffered holds the most recently received messages, to be logged if the handler fails
+	buffered []bufferedMessage
+}
+
+type bufferedMessage struct {
+	msg   any
+	index int
+}
+
+func (p *payloadLoggingHandlerConn) Receive(msg any) error {
+	if err := p.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+
+	if p.plan.logRequests {
+		keysAndValues := []any{"direction", streamDirectionReceive, "index", p.receiveIndex}
+		if p.receiveIndex == 0 {
+			keysAndValues = append(keysAndValues, p.interceptor.headers.requestValues(p.RequestHeader())...)
+		}
+		p.interceptor.emit(p.plan, p.log, msg, "request object", keysAndValues...)
+	}
+	if p.plan.bufferRequests {
+		if cloned := cloneMessage(msg); cloned != nil {
+			if len(p.buffered) == p.interceptor.errorRequestBufferSize {
+				p.buffered = p.buffered[1:]
+			}
+			p.buffered = append(p.buffered, bufferedMessage{msg: cloned, index: p.receiveIndex})
+		}
+	}
+	p.receiveIndex++
+
+	return nil
+}
+
+func (p *payloadLoggingHandlerConn) Send(msg any) error {
+	if p.plan.logResponses {
+		keysAndValues := []any{"direction", streamDirectionSend, "index", p.sendIndex}
+		if p.sendIndex == 0 {
+			keysAndValues = append(keysAndValues, p.interceptor.headers.responseValues(p.ResponseHeader(), nil)...)
+		}
+		p.interceptor.emit(p.plan, p.log, msg, "response object", keysAndValues...)
+	}
+	p.sendIndex++
+
+	return p.StreamingHandlerConn.Send(msg)
+}
@@ -0,0 +1,339 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+// echoStreamHandler sends each received message back to the client
+func echoStreamHandler(ctx context.Context, conn connect.StreamingHandlerConn) error {
+	for {
+		msg := &wrapperspb.StringValue{}
+		if err := conn.Receive(msg); err != nil {
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if err := conn.Send(msg); err != nil {
+			return err
+		}
+	}
+}
+
+func TestPayloadLoggingErrorRequests(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name     string
+		config   PayloadLoggingInterceptorConfig
+		err      error
+		expected []string
+	}{
+		{
+			name: "success not logged",
+			config: PayloadLoggingInterceptorConfig{
+				ErrorRequestMethods: "*",
+			},
+			err:      nil,
+			expected: []string{},
+		},
+		{
+			name: "any error logged",
+			config: PayloadLoggingInterceptorConfig{
+				ErrorRequestMethods: "*",
+			},
+			err:      connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expected: []string{"failed request object"},
+		},
+		{
+			name: "matching code logged",
+			config: PayloadLoggingInterceptorConfig{
+				ErrorRequestMethods: "*",
+				ErrorCodes:          []connect.Code{connect.CodeInternal},
+			},
+			err:      connect.NewError(connect.CodeInternal, errors.New("broken")),
+			expected: []string{"failed request object"},
+		},
+		{
+			name: "non-matching code not logged",
+			config: PayloadLoggingInterceptorConfig{
+				ErrorRequestMethods: "*",
+				ErrorCodes:          []connect.Code{connect.CodeInternal},
+			},
+			err:      connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expected: []string{},
+		},
+		{
+			name: "already logged request not logged again",
+			config: PayloadLoggingInterceptorConfig{
+				RequestMethods:      "*",
+				ErrorRequestMethods: "*",
+			},
+			err:      connect.NewError(connect.CodeInternal, errors.New("broken")),
+			expected: []string{"request object"},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines := []map[string]any{}
+			log := funcr.NewJSON(func(obj string) {
+				line := map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				lines = append(lines, line)
+			}, funcr.Options{})
+			tc.config.Logger = &log
+
+			unary := NewPayloadLoggingInterceptor(tc.config).WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+				// Handlers modifying the request should not affect what is logged
+				req.Any().(*wrapperspb.StringValue).Value = "modified"
+				if tc.err != nil {
+					return nil, tc.err
+				}
+				return connect.NewResponse(&wrapperspb.StringValue{}), nil
+			})
+
+			_, err := unary(context.Background(), connect.NewRequest(wrapperspb.String("original")))
+			require.Equal(t, tc.err, err)
+
+			messages := []string{}
+			for _, line := range lines {
+				messages = append(messages, line["msg"].(string))
+				if line["msg"] == "failed request object" {
+					require.Equal(t, `"original"`, line["object"])
+					require.Equal(t, connect.CodeOf(tc.err).String(), line["code"])
+				}
+			}
+			require.Equal(t, tc.expected, messages)
+		})
+	}
+}
+
+func TestPayloadLoggingStreaming(t *testing.T) {
+	t.Parallel()
+
+	type logLine struct {
+		Msg       string
+		Direction string
+		Index     float64
+		Object    string
+	}
+
+	testData := []struct {
+		name     string
+		config   PayloadLoggingInterceptorConfig
+		expected []logLine
+	}{
+		{
+			name:     "nothing configured",
+			config:   PayloadLoggingInterceptorConfig{},
+			expected: []logLine{},
+		},
+		{
+			name: "requests only",
+			config: PayloadLoggingInterceptorConfig{
+				RequestMethods: "*",
+			},
+			expected: []logLine{
+				{Msg: "request object", Direction: "receive", Index: 0, Object: `"a"`},
+				{Msg: "request object", Direction: "receive", Index: 1, Object: `"b"`},
+			},
+		},
+		{
+			name: "responses only",
+			config: PayloadLoggingInterceptorConfig{
+				ResponseMethods: "*",
+			},
+			expected: []logLine{
+				{Msg: "response object", Direction: "send", Index: 0, Object: `"a"`},
+				{Msg: "response object", Direction: "send", Index: 1, Object: `"b"`},
+			},
+		},
+		{
+			name: "both directions",
+			config: PayloadLoggingInterceptorConfig{
+				RequestMethods:  "*",
+				ResponseMethods: "*",
+			},
+			expected: []logLine{
+				{Msg: "request object", Direction: "receive", Index: 0, Object: `"a"`},
+				{Msg: "response object", Direction: "send", Index: 0, Object: `"a"`},
+				{Msg: "request object", Direction: "receive", Index: 1, Object: `"b"`},
+				{Msg: "response object", Direction: "send", Index: 1, Object: `"b"`},
+			},
+		},
+		{
+			name: "other method",
+			config: PayloadLoggingInterceptorConfig{
+				RequestMethods:  "/pkg.v1.Service/Other",
+				ResponseMethods: "/pkg.v1.Service/Other",
+			},
+			expected: []logLine{},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines := []logLine{}
+			log := funcr.NewJSON(func(obj string) {
+				line := logLine{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				lines = append(lines, line)
+			}, funcr.Options{})
+			tc.config.Logger = &log
+
+			stream := NewPayloadLoggingInterceptor(tc.config).WrapStreamingHandler(echoStreamHandler)
+			conn := newTestStreamConn("/pkg.v1.Service/Echo", wrapperspb.String("a"), wrapperspb.String("b"))
+
+			require.NoError(t, stream(context.Background(), conn))
+			require.Len(t, conn.sent, 2)
+			require.Equal(t, tc.expected, lines)
+		})
+	}
+}
+
+func TestPayloadLoggingPlan(t *testing.T) {
+	t.Parallel()
+
+	const procedure = "/pkg.v1.Service/Get"
+
+	testData := []struct {
+		name     string
+		config   PayloadLoggingInterceptorConfig
+		header   http.Header
+		sampled  bool
+		expected payloadLogPlan
+	}{
+		{
+			name:     "nothing configured",
+			sampled:  true,
+			expected: payloadLogPlan{procedure: procedure},
+		},
+		{
+			name: "matching methods",
+			config: PayloadLoggingInterceptorConfig{
+				RequestMethods:  "*",
+				ResponseMethods: procedure,
+			},
+			sampled:  true,
+			expected: payloadLogPlan{procedure: procedure, logRequests: true, logResponses: true},
+		},
+		{
+			name: "failures buffered",
+			config: PayloadLoggingInterceptorConfig{
+				ErrorRequestMethods: "*",
+			},
+			sampled:  true,
+			expected: payloadLogPlan{procedure: procedure, bufferRequests: true},
+		},
+		{
+			name: "sampled out",
+			config: PayloadLoggingInterceptorConfig{
+				RequestMethods:  "*",
+				ResponseMethods: "*",
+				SampleRate:      10,
+			},
+			sampled:  false,
+			expected: payloadLogPlan{procedure: procedure},
+		},
+		{
+			name: "sampled out still buffers failures",
+			config: PayloadLoggingInterceptorConfig{
+				RequestMethods:      "*",
+				ErrorRequestMethods: "*",
+				SampleRate:          10,
+			},
+			sampled:  false,
+			expected: payloadLogPlan{procedure: procedure, bufferRequests: true},
+		},
+		{
+			name: "forced by header",
+			config: PayloadLoggingInterceptorConfig{
+				ForceLogHeader: "X-Force-Log",
+				SampleRate:     10,
+			},
+			header:   http.Header{"X-Force-Log": {"1"}},
+			sampled:  false,
+			expected: payloadLogPlan{procedure: procedure, logRequests: true, logResponses: true, forced: true},
+		},
+		{
+			name: "forced by secret",
+			config: PayloadLoggingInterceptorConfig{
+				ForceLogHeader:      "X-Force-Log",
+				ForceLogHeaderValue: "secret",
+			},
+			header:   http.Header{"X-Force-Log": {"secret"}},
+			sampled:  true,
+			expected: payloadLogPlan{procedure: procedure, logRequests: true, logResponses: true, forced: true},
+		},
+		{
+			name: "wrong secret not forced",
+			config: PayloadLoggingInterceptorConfig{
+				ForceLogHeader:      "X-Force-Log",
+				ForceLogHeaderValue: "secret",
+			},
+			header:   http.Header{"X-Force-Log": {"guess"}},
+			sampled:  true,
+			expected: payloadLogPlan{procedure: procedure},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			inter := NewPayloadLoggingInterceptor(tc.config)
+			inter.sampled = func(int) bool { return tc.sampled }
+
+			header := tc.header
+			if header == nil {
+				header = http.Header{}
+			}
+			require.Equal(t, tc.expected, inter.plan(connect.Spec{Procedure: procedure}, header))
+		})
+	}
+}
+
+func TestPayloadLoggingForcedRateLimit(t *testing.T) {
+	t.Parallel()
+
+	messages := []string{}
+	log := funcr.NewJSON(func(obj string) {
+		line := map[string]any{}
+		require.NoError(t, json.Unmarshal([]byte(obj), &line))
+		messages = append(messages, line["msg"].(string))
+	}, funcr.Options{})
+
+	inter := NewPayloadLoggingInterceptor(PayloadLoggingInterceptorConfig{
+		Logger:            &log,
+		ForceLogHeader:    "X-Force-Log",
+		ForceLogRateLimit: &RateLimit{PerSecond: 1, Burst: 2},
+	})
+	inter.forceLimiter.now = func() time.Time { return time.Unix(0, 0) }
+
+	unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		return connect.NewResponse(wrapperspb.String("response")), nil
+	})
+
+	// The force bucket is shared across procedures, so the second call is not logged
+	for range 2 {
+		req := connect.NewRequest(wrapperspb.String("request"))
+		req.Header().Set("X-Force-Log", "1")
+		_, err := unary(context.Background(), req)
+		require.NoError(t, err)
+	}
+
+	require.Equal(t, []string{"request object", "response object"}, messages)
+}
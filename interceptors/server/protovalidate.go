@@ -6,58 +6,113 @@ import (
 
 	"connectrpc.com/connect"
 	"buf.build/go/protovalidate"
+	"github.com/nicjohnson145/connecthelp/interceptors/matcher"
 	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
-	"github.com/nicjohnson145/hlp/set"
 	"google.golang.org/protobuf/reflect/protoreflect"
 )
 
 type ProtovalidateInterceptorConfig struct {
 	// SkipMethods is a comma separated list of methods to skip validation for
 	SkipMethods string
+	// SkipMatcher optionally selects the methods to skip validation for, taking precedence over SkipMethods
+	SkipMatcher *matcher.MethodMatcher
+	// TerminateStreamOnInvalid ends a streaming call on the first invalid message received, returning the validation
+	// error to the client regardless of what the handler returns. By default the error is only returned from
+	// Receive, and the handler decides how to proceed
+	TerminateStreamOnInvalid bool
 }
 
 func NewProtovalidateInterceptor(config ProtovalidateInterceptorConfig) *ProtovalidateInterceptor {
-	toFilter := func(str string) validateSkipFilter {
-		switch str {
-		case "":
-			return func(s string) bool { return false }
-		default:
-			methodSet := set.New(strings.Split(str, ",")...)
-			return func(s string) bool { return methodSet.Contains(s) }
-		}
+	skipMatcher := config.SkipMatcher
+	if skipMatcher == nil {
+		skipMatcher = matcher.Exact(strings.Split(config.SkipMethods, ",")...)
 	}
 
 	return &ProtovalidateInterceptor{
-		skipFilter: toFilter(config.SkipMethods),
+		skipMatcher:              skipMatcher,
+		terminateStreamOnInvalid: config.TerminateStreamOnInvalid,
 	}
 }
 
 var _ connect.Interceptor = (*ProtovalidateInterceptor)(nil)
 
-type validateSkipFilter func(str string) bool
-
 type ProtovalidateInterceptor struct {
 	unimplemented.UnimplementedInterceptor
-	skipFilter validateSkipFilter
+	skipMatcher              *matcher.MethodMatcher
+	terminateStreamOnInvalid bool
 }
 
 func (p *ProtovalidateInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
-		if !p.skipFilter(req.Spec().Procedure) {
-			if objProto, ok := req.Any().(protoreflect.ProtoMessage); ok {
-				if err := protovalidate.Validate(objProto); err != nil {
-					return nil, connect.NewError(connect.CodeInvalidArgument, err)
-				}
+		if !p.skipMatcher.MatchesSpec(req.Spec()) {
+			if err := validateMessage(req.Any()); err != nil {
+				return nil, err
 			}
 		}
 		return next(ctx, req)
 	})
 }
 
-// TODO: implement streaming. Probably implmenting the StreamingHandlerConn interface, and "peeking" the initial client
-// request, and then logging the responses in the overwritten `Send` method
 func (p *ProtovalidateInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
 	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
-		return next(ctx, conn)
+		if p.skipMatcher.MatchesSpec(conn.Spec()) {
+			return next(ctx, conn)
+		}
+
+		if !p.terminateStreamOnInvalid {
+			return next(ctx, &protovalidateHandlerConn{StreamingHandlerConn: conn})
+		}
+
+		ctx, cancel := context.WithCancelCause(ctx)
+		defer cancel(nil)
+
+		validatingConn := &protovalidateHandlerConn{
+			StreamingHandlerConn: conn,
+			onInvalid:            cancel,
+		}
+		err := next(ctx, validatingConn)
+		if validatingConn.invalidErr != nil {
+			return validatingConn.invalidErr
+		}
+		return err
 	})
 }
+
+func validateMessage(msg any) error {
+	objProto, ok := msg.(protoreflect.ProtoMessage)
+	if !ok {
+		return nil
+	}
+	if err := protovalidate.Validate(objProto); err != nil {
+		return connect.NewError(connect.CodeInvalidArgument, err)
+	}
+	return nil
+}
+
+// protovalidateHandlerConn wraps a streaming connection, validating each message received from the client
+type protovalidateHandlerConn struct {
+	connect.StreamingHandlerConn
+	// onInvalid is optionally called with the first validation failure, used to end the stream early
+	onInvalid  context.CancelCauseFunc
+	invalidErr error
+}
+
+func (p *protovalidateHandlerConn) Receive(msg any) error {
+	if p.invalidErr != nil {
+		return p.invalidErr
+	}
+
+	if err := p.StreamingHandlerConn.Receive(msg); err != nil {
+		return err
+	}
+
+	if err := validateMessage(msg); err != nil {
+		if p.onInvalid != nil {
+			p.invalidErr = err
+			p.onInvalid(err)
+		}
+		return err
+	}
+
+	return nil
+}
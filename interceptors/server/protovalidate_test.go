@@ -0,0 +1,133 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"buf.build/gen/go/bufbuild/protovalidate/protocolbuffers/go/buf/validate"
+	"connectrpc.com/connect"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/descriptorpb"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+func TestProtovalidateStreaming(t *testing.T) {
+	t.Parallel()
+
+	desc := protovalidateTestDescriptor(t)
+	newMsg := func(name string) proto.Message {
+		msg := dynamicpb.NewMessage(desc)
+		msg.Set(desc.Fields().ByName("name"), protoreflect.ValueOfString(name))
+		return msg
+	}
+
+	testData := []struct {
+		name             string
+		config           ProtovalidateInterceptorConfig
+		requests         []proto.Message
+		expectedReceives []connect.Code
+		expectedErr      connect.Code
+		expectedCanceled bool
+	}{
+		{
+			name:             "all valid",
+			requests:         []proto.Message{newMsg("a"), newMsg("b")},
+			expectedReceives: []connect.Code{0, 0},
+		},
+		{
+			name:             "invalid returned from receive",
+			requests:         []proto.Message{newMsg("a"), newMsg(""), newMsg("b")},
+			expectedReceives: []connect.Code{0, connect.CodeInvalidArgument, 0},
+		},
+		{
+			name: "skipped method",
+			config: ProtovalidateInterceptorConfig{
+				SkipMethods: "/pkg.v1.Service/Stream",
+			},
+			requests:         []proto.Message{newMsg("")},
+			expectedReceives: []connect.Code{0},
+		},
+		{
+			name: "terminate on invalid",
+			config: ProtovalidateInterceptorConfig{
+				TerminateStreamOnInvalid: true,
+			},
+			requests:         []proto.Message{newMsg("a"), newMsg(""), newMsg("b")},
+			expectedReceives: []connect.Code{0, connect.CodeInvalidArgument, connect.CodeInvalidArgument},
+			expectedErr:      connect.CodeInvalidArgument,
+			expectedCanceled: true,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			receives := []connect.Code{}
+			canceled := false
+			stream := NewProtovalidateInterceptor(tc.config).WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+				// Keep receiving regardless of validation errors, the handler returning nil must not mask termination
+				for {
+					err := conn.Receive(dynamicpb.NewMessage(desc))
+					if errors.Is(err, io.EOF) || len(receives) == len(tc.expectedReceives) {
+						break
+					}
+					code := connect.Code(0)
+					if err != nil {
+						code = connect.CodeOf(err)
+					}
+					receives = append(receives, code)
+				}
+				canceled = ctx.Err() != nil
+				return nil
+			})
+
+			err := stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Stream", tc.requests...))
+			require.Equal(t, tc.expectedReceives, receives)
+			require.Equal(t, tc.expectedCanceled, canceled)
+			if tc.expectedErr == 0 {
+				require.NoError(t, err)
+			} else {
+				require.Equal(t, tc.expectedErr, connect.CodeOf(err))
+			}
+		})
+	}
+}
+
+// protovalidateTestDescriptor builds a message with a single name field, which must not be empty
+func protovalidateTestDescriptor(t *testing.T) protoreflect.MessageDescriptor {
+	t.Helper()
+
+	nameOptions := &descriptorpb.FieldOptions{}
+	proto.SetExtension(nameOptions, validate.E_Field, &validate.FieldRules{
+		Type: &validate.FieldRules_String_{String_: &validate.StringRules{MinLen: proto.Uint64(1)}},
+	})
+
+	file, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("test/v1/validated.proto"),
+		Package: proto.String("test.v1"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{
+				Name: proto.String("Validated"),
+				Field: []*descriptorpb.FieldDescriptorProto{
+					{
+						Name:     proto.String("name"),
+						JsonName: proto.String("name"),
+						Number:   proto.Int32(1),
+						Label:    descriptorpb.FieldDescriptorProto_LABEL_OPTIONAL.Enum(),
+						Type:     descriptorpb.FieldDescriptorProto_TYPE_STRING.Enum(),
+						Options:  nameOptions,
+					},
+				},
+			},
+		},
+	}, nil)
+	require.NoError(t, err)
+
+	return file.Messages().ByName("Validated")
+}
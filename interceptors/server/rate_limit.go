@@ -0,0 +1,92 @@
+package server
+
+import (
+	"math/rand/v2"
+	"sync"
+	"time"
+)
+
+// RateLimit is a token bucket rate limit, allowing PerSecond events on average with bursts of up to Burst events
+type RateLimit struct {
+	PerSecond float64
+	Burst     int
+}
+
+type tokenBucket struct {
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+func (t *tokenBucket) allow(now time.Time) bool {
+	t.tokens += now.Sub(t.last).Seconds() * t.limit.PerSecond
+	if burst := float64(max(t.limit.Burst, 1)); t.tokens > burst {
+		t.tokens = burst
+	}
+	t.last = now
+
+	if t.tokens < 1 {
+		return false
+	}
+	t.tokens--
+	return true
+}
+
+// rateLimiter holds a token bucket per procedure. A nil rateLimiter allows everything
+type rateLimiter struct {
+	defaultLimit    *RateLimit
+	procedureLimits map[string]RateLimit
+	now             func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*tokenBucket
+}
+
+func newRateLimiter(defaultLimit *RateLimit, procedureLimits map[string]RateLimit) *rateLimiter {
+	if defaultLimit == nil && len(procedureLimits) == 0 {
+		return nil
+	}
+
+	return &rateLimiter{
+		defaultLimit:    defaultLimit,
+		procedureLimits: procedureLimits,
+		now:             time.Now,
+		buckets:         map[string]*tokenBucket{},
+	}
+}
+
+func (r *rateLimiter) allow(procedure string) bool {
+	if r == nil {
+		return true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := r.now()
+
+	bucket, ok := r.buckets[procedure]
+	if !ok {
+		limit, ok := r.procedureLimits[procedure]
+		if !ok {
+			if r.defaultLimit == nil {
+				return true
+			}
+			limit = *r.defaultLimit
+		}
+
+		bucket = &tokenBucket{
+			limit:  limit,
+			tokens: float64(max(limit.Burst, 1)),
+			last:   now,
+		}
+		r.buckets[procedure] = bucket
+	}
+
+	return bucket.allow(now)
+}
+
+// sampled reports whether a call should be logged when sampling 1 in rate calls. Rates of 1 or less sample everything
+func sampled(rate int) bool {
+	return rate <= 1 || rand.IntN(rate) == 0
+}
@@ -0,0 +1,67 @@
+package server
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimiter(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(0, 0)
+	limiter := newRateLimiter(&RateLimit{PerSecond: 1, Burst: 2}, map[string]RateLimit{
+		"/pkg.v1.Service/Hot": {PerSecond: 0.5, Burst: 1},
+	})
+	limiter.now = func() time.Time { return now }
+
+	// Burst is available immediately, then exhausted
+	require.True(t, limiter.allow("/pkg.v1.Service/Get"))
+	require.True(t, limiter.allow("/pkg.v1.Service/Get"))
+	require.False(t, limiter.allow("/pkg.v1.Service/Get"))
+
+	// Procedures have independent buckets, with their own limits
+	require.True(t, limiter.allow("/pkg.v1.Service/Hot"))
+	require.False(t, limiter.allow("/pkg.v1.Service/Hot"))
+
+	// Tokens refill over time
+	now = now.Add(time.Second)
+	require.True(t, limiter.allow("/pkg.v1.Service/Get"))
+	require.False(t, limiter.allow("/pkg.v1.Service/Get"))
+	require.False(t, limiter.allow("/pkg.v1.Service/Hot"))
+
+	now = now.Add(time.Second)
+	require.True(t, limiter.allow("/pkg.v1.Service/Hot"))
+}
+
+func TestRateLimiterDisabled(t *testing.T) {
+	t.Parallel()
+
+	limiter := newRateLimiter(nil, nil)
+	require.Nil(t, limiter)
+	require.True(t, limiter.allow("/pkg.v1.Service/Get"))
+
+	limiter = newRateLimiter(nil, map[string]RateLimit{"/pkg.v1.Service/Hot": {PerSecond: 1, Burst: 1}})
+	for range 5 {
+		require.True(t, limiter.allow("/pkg.v1.Service/Get"))
+	}
+}
+
+func TestSampled(t *testing.T) {
+	t.Parallel()
+
+	for _, rate := range []int{-1, 0, 1} {
+		for range 10 {
+			require.True(t, sampled(rate))
+		}
+	}
+
+	hits := 0
+	for range 10000 {
+		if sampled(10) {
+			hits++
+		}
+	}
+	require.InDelta(t, 1000, hits, 300)
+}
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+)
+
+// RequestIDGenerator generates the request IDs attached by the ContextLoggerInterceptor. Implementations must be safe
+// for concurrent use
+type RequestIDGenerator interface {
+	NewRequestID() string
+}
+
+var (
+	_ RequestIDGenerator = (*ULIDGenerator)(nil)
+	_ RequestIDGenerator = (*UUIDv4Generator)(nil)
+	_ RequestIDGenerator = (*UUIDv7Generator)(nil)
+	_ RequestIDGenerator = (*SequenceGenerator)(nil)
+)
+
+// MaxRequestIDLength is the maximum length of an inbound request ID accepted by ValidRequestID
+const MaxRequestIDLength = 128
+
+// ValidRequestID reports whether the inbound request ID is safe to attach to logs & echo back to the caller. IDs must
+// be non-empty, at most MaxRequestIDLength bytes, and contain only ASCII letters, digits, and any of "-_.:"
+func ValidRequestID(requestID string) bool {
+	if requestID == "" || len(requestID) > MaxRequestIDLength {
+		return false
+	}
+
+	for _, c := range []byte(requestID) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.', c == ':':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// NewULIDGenerator returns a generator of ULIDs, using monotonic entropy so IDs generated within the same millisecond
+// still sort in generation order
+func NewULIDGenerator() *ULIDGenerator {
+	return &ULIDGenerator{
+		entropy: ulid.Monotonic(rand.Reader, 0),
+	}
+}
+
+type ULIDGenerator struct {
+	mu      sync.Mutex
+	entropy *ulid.MonotonicEntropy
+}
+
+func (u *ULIDGenerator) NewRequestID() string {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	id, err := ulid.New(ulid.Now(), u.entropy)
+	if err != nil {
+		// Monotonic entropy overflowed within the current millisecond, fall back to a non-monotonic ID
+		return ulid.Make().String()
+	}
+	return id.String()
+}
+
+// NewUUIDv4Generator returns a generator of random (version 4) UUIDs
+func NewUUIDv4Generator() *UUIDv4Generator {
+	return &UUIDv4Generator{}
+}
+
+type UUIDv4Generator struct{}
+
+func (u *UUIDv4Generator) NewRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[:])
+
+	return formatUUID(id, 4)
+}
+
+// NewUUIDv7Generator returns a generator of time-ordered (version 7) UUIDs
+func NewUUIDv7Generator() *UUIDv7Generator {
+	return &UUIDv7Generator{
+		now: time.Now,
+	}
+}
+
+type UUIDv7Generator struct {
+	now func() time.Time
+}
+
+func (u *UUIDv7Generator) NewRequestID() string {
+	var id [16]byte
+	_, _ = rand.Read(id[6:])
+
+	// The first 48 bits are the big-endian unix timestamp in milliseconds
+	var ts [8]byte
+	binary.BigEndian.PutUint64(ts[:], uint64(u.now().UnixMilli()))
+	copy(id[:6], ts[2:])
+
+	return formatUUID(id, 7)
+}
+
+// formatUUID sets the version & RFC 9562 variant bits on the given ID, and formats it in the canonical 8-4-4-4-12 form
+func formatUUID(id [16]byte, version byte) string {
+	id[6] = (id[6] & 0x0f) | (version << 4)
+	id[8] = (id[8] & 0x3f) | 0x80
+
+	var buf [36]byte
+	hex.Encode(buf[0:8], id[0:4])
+	buf[8] = '-'
+	hex.Encode(buf[9:13], id[4:6])
+	buf[13] = '-'
+	hex.Encode(buf[14:18], id[6:8])
+	buf[18] = '-'
+	hex.Encode(buf[19:23], id[8:10])
+	buf[23] = '-'
+	hex.Encode(buf[24:], id[10:])
+
+	return string(buf[:])
+}
+
+// NewSequenceGenerator returns a deterministic generator producing prefix-1, prefix-2, etc. Intended for tests where
+// predictable request IDs are required
+func NewSequenceGenerator(prefix string) *SequenceGenerator {
+	return &SequenceGenerator{
+		prefix: prefix,
+	}
+}
+
+type SequenceGenerator struct {
+	prefix  string
+	counter atomic.Uint64
+}
+
+func (s *SequenceGenerator) NewRequestID() string {
+	return fmt.Sprintf("%v-%v", s.prefix, s.counter.Add(1))
+}
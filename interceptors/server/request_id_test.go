@@ -0,0 +1,84 @@
+package server
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/oklog/ulid/v2"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRequestIDGenerators(t *testing.T) {
+	t.Parallel()
+
+	uuidPattern := func(version string) *regexp.Regexp {
+		return regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-` + version + `[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	}
+
+	t.Run("ulid", func(t *testing.T) {
+		t.Parallel()
+
+		gen := NewULIDGenerator()
+		first := gen.NewRequestID()
+		second := gen.NewRequestID()
+
+		_, err := ulid.ParseStrict(first)
+		require.NoError(t, err)
+		require.Less(t, first, second)
+	})
+
+	t.Run("uuidv4", func(t *testing.T) {
+		t.Parallel()
+
+		gen := NewUUIDv4Generator()
+		require.Regexp(t, uuidPattern("4"), gen.NewRequestID())
+		require.NotEqual(t, gen.NewRequestID(), gen.NewRequestID())
+	})
+
+	t.Run("uuidv7", func(t *testing.T) {
+		t.Parallel()
+
+		gen := NewUUIDv7Generator()
+		gen.now = func() time.Time { return time.UnixMilli(0x0123456789ab) }
+
+		id := gen.NewRequestID()
+		require.Regexp(t, uuidPattern("7"), id)
+		require.Equal(t, "01234567-89ab-7", id[:15])
+	})
+
+	t.Run("sequence", func(t *testing.T) {
+		t.Parallel()
+
+		gen := NewSequenceGenerator("req")
+		require.Equal(t, "req-1", gen.NewRequestID())
+		require.Equal(t, "req-2", gen.NewRequestID())
+	})
+}
+
+func TestValidRequestID(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name      string
+		requestID string
+		expected  bool
+	}{
+		{name: "ulid", requestID: "01ARZ3NDEKTSV4RRFFQ69G5FAV", expected: true},
+		{name: "uuid", requestID: "0b7e6c4e-8f3a-4d0e-9a53-2f9e4c1d7b6a", expected: true},
+		{name: "punctuation", requestID: "svc_a.req:42", expected: true},
+		{name: "max length", requestID: strings.Repeat("a", MaxRequestIDLength), expected: true},
+		{name: "empty", requestID: "", expected: false},
+		{name: "too long", requestID: strings.Repeat("a", MaxRequestIDLength+1), expected: false},
+		{name: "space", requestID: "a b", expected: false},
+		{name: "newline", requestID: "a\nb", expected: false},
+		{name: "non ascii", requestID: "caf\u00e9", expected: false},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+			require.Equal(t, tc.expected, ValidRequestID(tc.requestID))
+		})
+	}
+}
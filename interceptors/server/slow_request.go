@@ -0,0 +1,132 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr"
+	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
+)
+
+const (
+	DefaultSlowRequestThreshold = 1 * time.Second
+)
+
+type SlowRequestInterceptorConfig struct {
+	// Logger is the optional logger the slow requests will be logged with, if not given, will attempt to use the
+	// context logger. If neither are present, no logging will be done
+	Logger *logr.Logger
+	// Threshold is the optional latency above which a completed request is logged. If not given will default to
+	// DefaultSlowRequestThreshold
+	Threshold *time.Duration
+	// ProcedureThresholds optionally overrides Threshold for specific procedures, keyed by the full procedure name
+	ProcedureThresholds map[string]time.Duration
+	// StuckThreshold is the optional latency after which a still running request is logged. If not given, running
+	// requests are not logged
+	StuckThreshold *time.Duration
+}
+
+func NewSlowRequestInterceptor(config SlowRequestInterceptorConfig) *SlowRequestInterceptor {
+	threshold := DefaultSlowRequestThreshold
+	if config.Threshold != nil {
+		threshold = *config.Threshold
+	}
+
+	return &SlowRequestInterceptor{
+		logger:              config.Logger,
+		threshold:           threshold,
+		procedureThresholds: config.ProcedureThresholds,
+		stuckThreshold:      config.StuckThreshold,
+		now:                 time.Now,
+	}
+}
+
+var _ connect.Interceptor = (*SlowRequestInterceptor)(nil)
+
+// SlowRequestInterceptor logs only the requests that exceed a latency threshold, and optionally warns about requests
+// that are still running past a "stuck" threshold, allowing pathological calls to be found without logging every
+// request.
+type SlowRequestInterceptor struct {
+	unimplemented.UnimplementedInterceptor
+	logger              *logr.Logger
+	threshold           time.Duration
+	procedureThresholds map[string]time.Duration
+	stuckThreshold      *time.Duration
+	now                 func() time.Time
+}
+
+func (s *SlowRequestInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
+	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+		log := s.getLogger(ctx).WithValues("path", req.Spec().Procedure)
+
+		done, stop := s.watch(log, req.Spec().Procedure, "request")
+		defer stop()
+
+		resp, err := next(ctx, req)
+		done(err)
+
+		return resp, err
+	})
+}
+
+func (s *SlowRequestInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
+	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+		log := s.getLogger(ctx).WithValues("path", conn.Spec().Procedure)
+
+		done, stop := s.watch(log, conn.Spec().Procedure, "stream")
+		defer stop()
+
+		err := next(ctx, conn)
+		done(err)
+
+		return err
+	})
+}
+
+// watch starts timing a request, logging a warning if it is still running past the stuck threshold. The returned done
+// function must be called once the request completes, and stop must be deferred so the stuck warning is cancelled
+// even if the handler panics
+func (s *SlowRequestInterceptor) watch(log logr.Logger, procedure string, kind string) (func(err error), func()) {
+	start := s.now()
+
+	var stuckTimer *time.Timer
+	if s.stuckThreshold != nil {
+		stuckTimer = time.AfterFunc(*s.stuckThreshold, func() {
+			log.Info(kind+" still running past stuck threshold", "elapsed", s.now().Sub(start), "stuck-threshold", *s.stuckThreshold)
+		})
+	}
+
+	stop := func() {
+		if stuckTimer != nil {
+			stuckTimer.Stop()
+		}
+	}
+
+	return func(err error) {
+		stop()
+
+		elapsed := s.now().Sub(start)
+		threshold := s.thresholdFor(procedure)
+		if elapsed < threshold {
+			return
+		}
+
+		log.Info("slow "+kind+" completed", "duration", elapsed, "threshold", threshold, "code", codeString(err))
+	}, stop
+}
+
+func (s *SlowRequestInterceptor) thresholdFor(procedure string) time.Duration {
+	if threshold, ok := s.procedureThresholds[procedure]; ok {
+		return threshold
+	}
+	return s.threshold
+}
+
+func (s *SlowRequestInterceptor) getLogger(ctx context.Context) logr.Logger {
+	if s.logger != nil {
+		return *s.logger
+	} else {
+		return logr.FromContextOrDiscard(ctx)
+	}
+}
@@ -0,0 +1,230 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr"
+	"github.com/go-logr/logr/funcr"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/emptypb"
+)
+
+// fakeClock is a manually advanced clock for tests
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (f *fakeClock) Now() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.now
+}
+
+func (f *fakeClock) Advance(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.now = f.now.Add(d)
+}
+
+func TestSlowRequestThreshold(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name         string
+		config       SlowRequestInterceptorConfig
+		elapsed      time.Duration
+		err          error
+		expectedCode string
+	}{
+		{
+			name:    "under default threshold",
+			config:  SlowRequestInterceptorConfig{},
+			elapsed: 10 * time.Millisecond,
+		},
+		{
+			name:         "over default threshold",
+			config:       SlowRequestInterceptorConfig{},
+			elapsed:      2 * time.Second,
+			expectedCode: "ok",
+		},
+		{
+			name: "over configured threshold",
+			config: SlowRequestInterceptorConfig{
+				Threshold: ptr(5 * time.Millisecond),
+			},
+			elapsed:      10 * time.Millisecond,
+			expectedCode: "ok",
+		},
+		{
+			name: "procedure override lowers threshold",
+			config: SlowRequestInterceptorConfig{
+				ProcedureThresholds: map[string]time.Duration{"/pkg.v1.Service/Get": 5 * time.Millisecond},
+			},
+			elapsed:      10 * time.Millisecond,
+			expectedCode: "ok",
+		},
+		{
+			name: "procedure override raises threshold",
+			config: SlowRequestInterceptorConfig{
+				ProcedureThresholds: map[string]time.Duration{"/pkg.v1.Service/Get": 5 * time.Second},
+			},
+			elapsed: 2 * time.Second,
+		},
+		{
+			name: "other procedure override ignored",
+			config: SlowRequestInterceptorConfig{
+				ProcedureThresholds: map[string]time.Duration{"/pkg.v1.Service/Other": 5 * time.Second},
+			},
+			elapsed:      2 * time.Second,
+			expectedCode: "ok",
+		},
+		{
+			name:         "error code logged",
+			config:       SlowRequestInterceptorConfig{},
+			elapsed:      2 * time.Second,
+			err:          connect.NewError(connect.CodeNotFound, errors.New("missing")),
+			expectedCode: "not_found",
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			lines := []map[string]any{}
+			log := funcr.NewJSON(func(obj string) {
+				line := map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				lines = append(lines, line)
+			}, funcr.Options{})
+			tc.config.Logger = &log
+
+			clock := &fakeClock{now: time.Now()}
+			inter := NewSlowRequestInterceptor(tc.config)
+			inter.now = clock.Now
+
+			stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+				clock.Advance(tc.elapsed)
+				return tc.err
+			})
+			_ = stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Get"))
+
+			if tc.expectedCode == "" {
+				require.Empty(t, lines)
+				return
+			}
+			require.Len(t, lines, 1)
+			require.Equal(t, "slow stream completed", lines[0]["msg"])
+			require.Equal(t, tc.expectedCode, lines[0]["code"])
+		})
+	}
+}
+
+func TestSlowRequestStuck(t *testing.T) {
+	t.Parallel()
+
+	t.Run("logged while running", func(t *testing.T) {
+		t.Parallel()
+
+		stuck := make(chan map[string]any, 1)
+		log := funcr.NewJSON(func(obj string) {
+			line := map[string]any{}
+			require.NoError(t, json.Unmarshal([]byte(obj), &line))
+			if line["msg"] == "request still running past stuck threshold" {
+				stuck <- line
+			}
+		}, funcr.Options{})
+
+		inter := NewSlowRequestInterceptor(SlowRequestInterceptorConfig{
+			Logger:         &log,
+			StuckThreshold: ptr(time.Millisecond),
+		})
+		unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			select {
+			case <-stuck:
+			case <-time.After(5 * time.Second):
+				t.Error("stuck request was not logged")
+			}
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		})
+
+		_, err := unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		require.NoError(t, err)
+	})
+
+	t.Run("stopped on completion", func(t *testing.T) {
+		t.Parallel()
+
+		mu := sync.Mutex{}
+		messages := []string{}
+		log := funcr.NewJSON(func(obj string) {
+			line := map[string]any{}
+			require.NoError(t, json.Unmarshal([]byte(obj), &line))
+			mu.Lock()
+			defer mu.Unlock()
+			messages = append(messages, line["msg"].(string))
+		}, funcr.Options{})
+
+		inter := NewSlowRequestInterceptor(SlowRequestInterceptorConfig{
+			Logger:         &log,
+			StuckThreshold: ptr(50 * time.Millisecond),
+		})
+		unary := inter.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			return connect.NewResponse(&emptypb.Empty{}), nil
+		})
+
+		_, err := unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		require.NoError(t, err)
+
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Empty(t, messages)
+	})
+
+	t.Run("stopped on panic", func(t *testing.T) {
+		t.Parallel()
+
+		mu := sync.Mutex{}
+		messages := []string{}
+		log := funcr.NewJSON(func(obj string) {
+			line := map[string]any{}
+			require.NoError(t, json.Unmarshal([]byte(obj), &line))
+			mu.Lock()
+			defer mu.Unlock()
+			messages = append(messages, line["msg"].(string))
+		}, funcr.Options{})
+
+		slow := NewSlowRequestInterceptor(SlowRequestInterceptorConfig{
+			Logger:         &log,
+			StuckThreshold: ptr(50 * time.Millisecond),
+		})
+		discard := logr.Discard()
+		recovery := NewPanicInterceptor(PanicInterceptorConfig{Logger: &discard})
+
+		unary := slow.WrapUnary(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
+			panic("boom")
+		})
+		stream := slow.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+			panic("boom")
+		})
+		unary = recovery.WrapUnary(unary)
+		stream = recovery.WrapStreamingHandler(stream)
+
+		_, err := unary(context.Background(), connect.NewRequest(&emptypb.Empty{}))
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+		err = stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Stream"))
+		require.Equal(t, connect.CodeInternal, connect.CodeOf(err))
+
+		time.Sleep(100 * time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
+		require.Empty(t, messages)
+	})
+}
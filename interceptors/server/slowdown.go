@@ -9,12 +9,27 @@ import (
 
 	"connectrpc.com/connect"
 	"github.com/go-logr/logr"
+	"github.com/nicjohnson145/connecthelp/interceptors/matcher"
 	"github.com/nicjohnson145/connecthelp/interceptors/unimplemented"
-	"github.com/nicjohnson145/hlp/set"
 )
 
 var (
 	ErrIncludedExcludedMutallyExclusiveError = errors.New("IncludedMethods and ExcludedMethods are mutually exclusive")
+	ErrUnknownSlowdownStreamMode             = errors.New("unknown StreamMode")
+)
+
+// SlowdownStreamMode controls where the delay is applied for streaming calls
+type SlowdownStreamMode string
+
+const (
+	// SlowdownStreamModeNone does not slow down streaming calls
+	SlowdownStreamModeNone SlowdownStreamMode = ""
+	// SlowdownStreamModeFirstSend delays before the first message is sent to the client
+	SlowdownStreamModeFirstSend SlowdownStreamMode = "first-send"
+	// SlowdownStreamModeBetweenSends delays before every message sent to the client except the first
+	SlowdownStreamModeBetweenSends SlowdownStreamMode = "between-sends"
+	// SlowdownStreamModeClose delays after the handler has returned, before the stream is closed
+	SlowdownStreamModeClose SlowdownStreamMode = "close"
 )
 
 const (
@@ -33,6 +48,12 @@ type SlowdownInterceptorConfig struct {
 	// ExcludedMethods is the comma-separated list of mehtods that should NOT be slowed down. This configuration param
 	// is mutally exclusive with IncludedMethods
 	ExcludedMethods string
+	// Matcher optionally selects the methods that should be slowed down, taking precedence over IncludedMethods and
+	// ExcludedMethods
+	Matcher *matcher.MethodMatcher
+	// StreamMode is the optional mode used to slow down streaming calls. If not given, streaming calls are not slowed
+	// down
+	StreamMode SlowdownStreamMode
 }
 
 func NewSlowdownInterceptor(config SlowdownInterceptorConfig) (*SlowdownInterceptor, error) {
@@ -40,20 +61,18 @@ func NewSlowdownInterceptor(config SlowdownInterceptorConfig) (*SlowdownIntercep
 		return nil, ErrIncludedExcludedMutallyExclusiveError
 	}
 
-	toFilter := func(inclusion string, exclusion string) slowdownFilter {
-		if inclusion != "" {
-			switch inclusion {
-			case "*":
-				return func(str string) bool { return true }
-			default:
-				methodSet := set.New(strings.Split(inclusion, ",")...)
-				return func(str string) bool { return methodSet.Contains(str) }
-			}
-		} else if exclusion != "" {
-			methodSet := set.New(strings.Split(exclusion, ",")...)
-			return func(str string) bool { return !methodSet.Contains(str) }
+	switch config.StreamMode {
+	case SlowdownStreamModeNone, SlowdownStreamModeFirstSend, SlowdownStreamModeBetweenSends, SlowdownStreamModeClose:
+	default:
+		return nil, fmt.Errorf("%w: %v", ErrUnknownSlowdownStreamMode, config.StreamMode)
+	}
+
+	filter := config.Matcher
+	if filter == nil {
+		if config.ExcludedMethods != "" {
+			filter = matcher.AllExcept(strings.Split(config.ExcludedMethods, ",")...)
 		} else {
-			return func(str string) bool { return false }
+			filter = matcher.FromMethodList(config.IncludedMethods)
 		}
 	}
 
@@ -63,47 +82,64 @@ func NewSlowdownInterceptor(config SlowdownInterceptorConfig) (*SlowdownIntercep
 	}
 
 	return &SlowdownInterceptor{
-		logger: config.Logger,
-		amount: amount,
-		filter: toFilter(config.IncludedMethods, config.ExcludedMethods),
+		logger:     config.Logger,
+		amount:     amount,
+		filter:     filter,
+		streamMode: config.StreamMode,
 	}, nil
 }
 
 var _ connect.Interceptor = (*SlowdownInterceptor)(nil)
 
-type slowdownFilter func(str string) bool
-
 // SlowdownInterceptor slows down responses, mostly useful in development testing where you want to test loading
 // states/etc but dont want to use browser network throttling so _everything_ is slow.
 type SlowdownInterceptor struct {
 	unimplemented.UnimplementedInterceptor
-	logger *logr.Logger
-	filter slowdownFilter
-	amount time.Duration
+	logger     *logr.Logger
+	filter     *matcher.MethodMatcher
+	amount     time.Duration
+	streamMode SlowdownStreamMode
 }
 
 func (p *SlowdownInterceptor) WrapUnary(next connect.UnaryFunc) connect.UnaryFunc {
 	return connect.UnaryFunc(func(ctx context.Context, req connect.AnyRequest) (connect.AnyResponse, error) {
 		resp, err := next(ctx, req)
 
-		if p.filter(req.Spec().Procedure) {
-			log := p.getLogger(ctx)
-			log.V(1).Info(fmt.Sprintf("slowing down response %v", p.amount), "interceptor", "slowdown")
-			time.Sleep(p.amount)
+		if p.filter.MatchesSpec(req.Spec()) {
+			p.sleep(p.getLogger(ctx), "response")
 		}
 
 		return resp, err
 	})
 }
 
-// TODO: implement streaming. Probably implmenting the StreamingHandlerConn interface, and "peeking" the initial client
-// request, and then logging the responses in the overwritten `Send` method
 func (p *SlowdownInterceptor) WrapStreamingHandler(next connect.StreamingHandlerFunc) connect.StreamingHandlerFunc {
 	return connect.StreamingHandlerFunc(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
-		return next(ctx, conn)
+		if p.streamMode == SlowdownStreamModeNone || !p.filter.MatchesSpec(conn.Spec()) {
+			return next(ctx, conn)
+		}
+
+		log := p.getLogger(ctx)
+
+		if p.streamMode == SlowdownStreamModeClose {
+			err := next(ctx, conn)
+			p.sleep(log, "stream close")
+			return err
+		}
+
+		return next(ctx, &slowdownHandlerConn{
+			StreamingHandlerConn: conn,
+			interceptor:          p,
+			log:                  log,
+		})
 	})
 }
 
+func (p *SlowdownInterceptor) sleep(log logr.Logger, what string) {
+	log.V(1).Info(fmt.Sprintf("slowing down %v %v", what, p.amount), "interceptor", "slowdown")
+	time.Sleep(p.amount)
+}
+
 func (p *SlowdownInterceptor) getLogger(ctx context.Context) logr.Logger {
 	if p.logger != nil {
 		return *p.logger
@@ -111,3 +147,27 @@ func (p *SlowdownInterceptor) getLogger(ctx context.Context) logr.Logger {
 		return logr.FromContextOrDiscard(ctx)
 	}
 }
+
+// slowdownHandlerConn wraps a streaming connection, delaying sends according to the interceptors stream mode
+type slowdownHandlerConn struct {
+	connect.StreamingHandlerConn
+	interceptor *SlowdownInterceptor
+	log         logr.Logger
+	sent        int
+}
+
+func (s *slowdownHandlerConn) Send(msg any) error {
+	switch s.interceptor.streamMode {
+	case SlowdownStreamModeFirstSend:
+		if s.sent == 0 {
+			s.interceptor.sleep(s.log, "first send")
+		}
+	case SlowdownStreamModeBetweenSends:
+		if s.sent > 0 {
+			s.interceptor.sleep(s.log, "send")
+		}
+	}
+	s.sent++
+
+	return s.StreamingHandlerConn.Send(msg)
+}
@@ -1,9 +1,15 @@
 package server
 
 import (
+	"context"
+	"encoding/json"
 	"testing"
+	"time"
 
+	"connectrpc.com/connect"
+	"github.com/go-logr/logr/funcr"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 func TestSlowdownFilter(t *testing.T) {
@@ -16,8 +22,8 @@ func TestSlowdownFilter(t *testing.T) {
 		expected bool
 	}{
 		{
-			name: "no config no match",
-			config: SlowdownInterceptorConfig{},
+			name:     "no config no match",
+			config:   SlowdownInterceptorConfig{},
 			input:    "a",
 			expected: false,
 		},
@@ -68,7 +74,116 @@ func TestSlowdownFilter(t *testing.T) {
 
 			inter, err := NewSlowdownInterceptor(tc.config)
 			require.NoError(t, err)
-			require.Equal(t, tc.expected, inter.filter(tc.input))
+			require.Equal(t, tc.expected, inter.filter.Matches(tc.input))
+		})
+	}
+}
+
+func TestSlowdownConfigValidation(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name     string
+		config   SlowdownInterceptorConfig
+		expected error
+	}{
+		{
+			name: "known stream mode",
+			config: SlowdownInterceptorConfig{
+				StreamMode: SlowdownStreamModeBetweenSends,
+			},
+			expected: nil,
+		},
+		{
+			name: "unknown stream mode",
+			config: SlowdownInterceptorConfig{
+				StreamMode: "sometimes",
+			},
+			expected: ErrUnknownSlowdownStreamMode,
+		},
+		{
+			name: "included and excluded",
+			config: SlowdownInterceptorConfig{
+				IncludedMethods: "a",
+				ExcludedMethods: "b",
+			},
+			expected: ErrIncludedExcludedMutallyExclusiveError,
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := NewSlowdownInterceptor(tc.config)
+			require.ErrorIs(t, err, tc.expected)
+		})
+	}
+}
+
+func TestSlowdownStreamMode(t *testing.T) {
+	t.Parallel()
+
+	testData := []struct {
+		name     string
+		mode     SlowdownStreamMode
+		expected []string
+	}{
+		{
+			name:     "none",
+			mode:     SlowdownStreamModeNone,
+			expected: []string{"sent", "sent", "sent"},
+		},
+		{
+			name: "first send",
+			mode: SlowdownStreamModeFirstSend,
+			expected: []string{
+				"slowing down first send 1ms", "sent", "sent", "sent",
+			},
+		},
+		{
+			name: "between sends",
+			mode: SlowdownStreamModeBetweenSends,
+			expected: []string{
+				"sent", "slowing down send 1ms", "sent", "slowing down send 1ms", "sent",
+			},
+		},
+		{
+			name: "close",
+			mode: SlowdownStreamModeClose,
+			expected: []string{
+				"sent", "sent", "sent", "slowing down stream close 1ms",
+			},
+		},
+	}
+	for _, tc := range testData {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			events := []string{}
+			log := funcr.NewJSON(func(obj string) {
+				line := map[string]any{}
+				require.NoError(t, json.Unmarshal([]byte(obj), &line))
+				events = append(events, line["msg"].(string))
+			}, funcr.Options{Verbosity: 1})
+
+			inter, err := NewSlowdownInterceptor(SlowdownInterceptorConfig{
+				Logger:          &log,
+				Amount:          ptr(time.Millisecond),
+				IncludedMethods: "*",
+				StreamMode:      tc.mode,
+			})
+			require.NoError(t, err)
+
+			stream := inter.WrapStreamingHandler(func(ctx context.Context, conn connect.StreamingHandlerConn) error {
+				for range 3 {
+					require.NoError(t, conn.Send(wrapperspb.String("msg")))
+					events = append(events, "sent")
+				}
+				return nil
+			})
+
+			require.NoError(t, stream(context.Background(), newTestStreamConn("/pkg.v1.Service/Stream")))
+			require.Equal(t, tc.expected, events)
 		})
 	}
 }
@@ -0,0 +1,65 @@
+package server
+
+import (
+	"io"
+	"net/http"
+
+	"connectrpc.com/connect"
+	"google.golang.org/protobuf/proto"
+)
+
+// testStreamConn is an in-memory StreamingHandlerConn for tests, receiving the given requests in order and recording
+// the messages sent
+type testStreamConn struct {
+	spec           connect.Spec
+	requests       []proto.Message
+	sent           []any
+	requestHeader  http.Header
+	responseHeader http.Header
+	trailer        http.Header
+}
+
+func newTestStreamConn(procedure string, requests ...proto.Message) *testStreamConn {
+	return &testStreamConn{
+		spec:           connect.Spec{Procedure: procedure, StreamType: connect.StreamTypeBidi},
+		requests:       requests,
+		requestHeader:  http.Header{},
+		responseHeader: http.Header{},
+		trailer:        http.Header{},
+	}
+}
+
+func (t *testStreamConn) Spec() connect.Spec {
+	return t.spec
+}
+
+func (t *testStreamConn) Peer() connect.Peer {
+	return connect.Peer{Addr: "127.0.0.1:1234", Protocol: connect.ProtocolConnect}
+}
+
+func (t *testStreamConn) Receive(msg any) error {
+	if len(t.requests) == 0 {
+		return io.EOF
+	}
+	proto.Reset(msg.(proto.Message))
+	proto.Merge(msg.(proto.Message), t.requests[0])
+	t.requests = t.requests[1:]
+	return nil
+}
+
+func (t *testStreamConn) RequestHeader() http.Header {
+	return t.requestHeader
+}
+
+func (t *testStreamConn) Send(msg any) error {
+	t.sent = append(t.sent, msg)
+	return nil
+}
+
+func (t *testStreamConn) ResponseHeader() http.Header {
+	return t.responseHeader
+}
+
+func (t *testStreamConn) ResponseTrailer() http.Header {
+	return t.trailer
+}